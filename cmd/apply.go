@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply PATCH [TARGET]",
+	Short: "Apply a unified diff to a file or directory tree",
+	Long: `apply reads a unified diff (use "-" for stdin) and applies it to TARGET,
+which is either a single file or the directory the patch's file names are
+relative to (the current directory by default). Hunks that have moved are
+found by searching around their recorded position, and up to --fuzz context
+lines may be ignored. Hunks that still do not apply are saved to FILE.rej.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run:  applyPatch,
+}
+
+func init() {
+	applyCmd.Flags().IntP("strip", "p", 0, "Strip this many leading path components from file names")
+	applyCmd.Flags().IntP("fuzz", "F", 2, "Maximum number of context lines to ignore when matching hunks")
+	applyCmd.Flags().Bool("dry-run", false, "Report what would change without writing any files")
+	applyCmd.Flags().BoolP("reverse", "R", false, "Undo the patch instead of applying it")
+
+	rootCmd.AddCommand(applyCmd)
+}
+
+func applyPatch(cmd *cobra.Command, args []string) {
+	strip, _ := cmd.Flags().GetInt("strip")
+	fuzz, _ := cmd.Flags().GetInt("fuzz")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	reverse, _ := cmd.Flags().GetBool("reverse")
+
+	var in io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening patch: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+	patches, err := diff.ParsePatch(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing patch: %v\n", err)
+		os.Exit(1)
+	}
+	if len(patches) == 0 {
+		fmt.Fprintln(os.Stderr, "Patch contains no file changes.")
+		os.Exit(1)
+	}
+	if reverse {
+		for i, p := range patches {
+			patches[i] = diff.InvertPatch(p)
+		}
+	}
+
+	target := "."
+	if len(args) == 2 {
+		target = args[1]
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error stating target: %v\n", err)
+		os.Exit(1)
+	}
+	if !info.IsDir() && len(patches) > 1 {
+		fmt.Fprintf(os.Stderr, "Patch touches %d files but %s is a single file.\n", len(patches), target)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, p := range patches {
+		path := target
+		if info.IsDir() {
+			name, err := patchTarget(p, strip)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				failed = true
+				continue
+			}
+			path = filepath.Join(target, name)
+		}
+		if !applyFilePatch(p, path, fuzz, dryRun) {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// patchTarget picks the file a patch applies to, preferring the new name
+// unless the file is being deleted. Names that would lead outside the
+// target directory, absolute ones or ones going up with "..", are refused.
+func patchTarget(p diff.FilePatch, strip int) (string, error) {
+	name := p.NewName
+	if name == diff.DevNull || name == "" {
+		name = p.OldName
+	}
+	parts := strings.Split(filepath.ToSlash(name), "/")
+	if strip >= len(parts) {
+		return "", fmt.Errorf("cannot strip %d components from %q", strip, name)
+	}
+	stripped := strings.Join(parts[strip:], "/")
+	if strings.HasPrefix(stripped, "/") || filepath.VolumeName(filepath.FromSlash(stripped)) != "" {
+		return "", fmt.Errorf("refusing to patch %q: absolute file name", stripped)
+	}
+	for _, part := range parts[strip:] {
+		if part == ".." {
+			return "", fmt.Errorf("refusing to patch %q: file name leads outside the target", stripped)
+		}
+	}
+	return filepath.FromSlash(stripped), nil
+}
+
+// applyFilePatch applies p to path and reports the outcome of every hunk.
+// It returns false when any hunk was rejected or the file could not be
+// read or written.
+func applyFilePatch(p diff.FilePatch, path string, fuzz int, dryRun bool) bool {
+	if dryRun {
+		fmt.Printf("checking file %s\n", path)
+	} else {
+		fmt.Printf("patching file %s\n", path)
+	}
+
+	var original []byte
+	if p.OldName != diff.DevNull {
+		var err error
+		original, err = os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
+			return false
+		}
+	} else if info, err := os.Stat(path); err == nil && (info.IsDir() || info.Size() > 0) {
+		// As GNU patch does, leave a file the patch would create alone
+		// when it already has content.
+		fmt.Printf("The patch would create %s, but that file already exists.\n", path)
+		if dryRun {
+			fmt.Printf("%d out of %d hunks FAILED\n", len(p.Hunks), len(p.Hunks))
+			return false
+		}
+		saveRejects(p, path, p.Hunks)
+		return false
+	}
+
+	result, err := diff.Apply(bytes.NewReader(original), p.Hunks, fuzz)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error applying patch to %s: %v\n", path, err)
+		return false
+	}
+	for i, res := range result.Hunks {
+		switch {
+		case !res.Applied:
+			fmt.Printf("Hunk #%d FAILED at %d.\n", i+1, res.Line)
+		case res.Offset != 0 && res.Fuzz != 0:
+			fmt.Printf("Hunk #%d succeeded at %d with fuzz %d (offset %d lines).\n", i+1, res.Line, res.Fuzz, res.Offset)
+		case res.Offset != 0:
+			fmt.Printf("Hunk #%d succeeded at %d (offset %d lines).\n", i+1, res.Line, res.Offset)
+		case res.Fuzz != 0:
+			fmt.Printf("Hunk #%d succeeded at %d with fuzz %d.\n", i+1, res.Line, res.Fuzz)
+		}
+	}
+	if dryRun {
+		if len(result.Rejected) > 0 {
+			fmt.Printf("%d out of %d hunks FAILED\n", len(result.Rejected), len(p.Hunks))
+		}
+		return len(result.Rejected) == 0
+	}
+
+	if len(result.Rejected) > 0 {
+		saveRejects(p, path, result.Rejected)
+	}
+
+	if p.NewName == diff.DevNull && len(result.Rejected) == 0 && len(result.Content) == 0 {
+		if err := os.Remove(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error removing %s: %v\n", path, err)
+			return false
+		}
+		return true
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating directory for %s: %v\n", path, err)
+		return false
+	}
+	if err := os.WriteFile(path, result.Content, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+		return false
+	}
+	return len(result.Rejected) == 0
+}
+
+// saveRejects writes the rejected hunks of p to path.rej.
+func saveRejects(p diff.FilePatch, path string, rejected []diff.Hunk) {
+	rejPath := path + ".rej"
+	fmt.Printf("%d out of %d hunks FAILED -- saving rejects to file %s\n", len(rejected), len(p.Hunks), rejPath)
+	var rej bytes.Buffer
+	diff.WritePatch(&rej, diff.FilePatch{OldName: p.OldName, NewName: p.NewName, Hunks: rejected})
+	if err := os.WriteFile(rejPath, rej.Bytes(), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", rejPath, err)
+	}
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+func TestApplyFilePatchCreate(t *testing.T) {
+	created := diff.FilePatch{OldName: diff.DevNull, NewName: "b/new.txt", Hunks: []diff.Hunk{{
+		OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1,
+		Lines: []diff.Diff{{Line: "new", Type: "add", NewLine: 1}},
+	}}}
+	tests := []struct {
+		name     string
+		exists   bool
+		existing string
+		wantOK   bool
+		want     string
+		wantRej  bool
+	}{
+		{name: "missing file", wantOK: true, want: "new\n"},
+		{name: "empty file", exists: true, wantOK: true, want: "new\n"},
+		{name: "file with content", exists: true, existing: "keep\n", want: "keep\n", wantRej: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "new.txt")
+			if tt.exists {
+				if err := os.WriteFile(path, []byte(tt.existing), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if ok := applyFilePatch(created, path, 0, false); ok != tt.wantOK {
+				t.Errorf("applyFilePatch() = %v, want %v", ok, tt.wantOK)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("file = %q, want %q", got, tt.want)
+			}
+			rej, err := os.ReadFile(path + ".rej")
+			if gotRej := err == nil && strings.Contains(string(rej), "+new"); gotRej != tt.wantRej {
+				t.Errorf("rejects saved = %v, want %v", gotRej, tt.wantRej)
+			}
+		})
+	}
+}
+
+func TestPatchTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   diff.FilePatch
+		strip   int
+		want    string
+		wantErr bool
+	}{
+		{name: "new name", patch: diff.FilePatch{OldName: "a/x.txt", NewName: "b/x.txt"}, strip: 1, want: "x.txt"},
+		{name: "deleted file", patch: diff.FilePatch{OldName: "a/dir/x.txt", NewName: diff.DevNull}, strip: 1, want: filepath.Join("dir", "x.txt")},
+		{name: "dot dot inside the tree", patch: diff.FilePatch{OldName: "x..txt", NewName: "x..txt"}, want: "x..txt"},
+		{name: "too many components", patch: diff.FilePatch{OldName: "a/x.txt", NewName: "b/x.txt"}, strip: 2, wantErr: true},
+		{name: "parent directory", patch: diff.FilePatch{OldName: "../x", NewName: "../x"}, wantErr: true},
+		{name: "parent directory after strip", patch: diff.FilePatch{OldName: "a/../../x", NewName: "b/../../x"}, strip: 1, wantErr: true},
+		{name: "absolute name", patch: diff.FilePatch{OldName: "/etc/passwd", NewName: "/etc/passwd"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := patchTarget(tt.patch, tt.strip)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("patchTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("patchTarget() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
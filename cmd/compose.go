@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+	"github.com/spf13/cobra"
+)
+
+var composeCmd = &cobra.Command{
+	Use:   "compose PATCH1 PATCH2",
+	Short: "Squash two consecutive unified diffs into one",
+	Long: `compose combines PATCH1, taking A to B, and PATCH2, taking B to C, into a
+single unified diff from A to C written to stdout. The intermediate files are
+not needed.`,
+	Args: cobra.ExactArgs(2),
+	Run:  composePatches,
+}
+
+func init() {
+	rootCmd.AddCommand(composeCmd)
+}
+
+func composePatches(cmd *cobra.Command, args []string) {
+	var sets [2][]diff.FilePatch
+	for i, path := range args {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening patch: %v\n", err)
+			os.Exit(1)
+		}
+		sets[i], err = diff.ParsePatch(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", path, err)
+			os.Exit(1)
+		}
+	}
+
+	composed, err := diff.ComposePatchSets(sets[0], sets[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error composing patches: %v\n", err)
+		os.Exit(1)
+	}
+	for _, p := range composed {
+		if len(p.Hunks) == 0 {
+			continue
+		}
+		if err := diff.WritePatch(os.Stdout, p); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing patch: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+	"github.com/san-kum/diff-dance/pkg/display"
+	"github.com/spf13/cobra"
+)
+
+// externalDiff is a file pair as git passes it to an external diff driver:
+//
+//	path old-file old-hex old-mode new-file new-hex new-mode [new-path info]
+//
+// The side of an added or removed file is /dev/null with "." for its hash
+// and mode. The last two arguments are only given for renames and copies;
+// info then holds the extended header lines git would print, such as
+// "rename from".
+type externalDiff struct {
+	path, oldFile, oldHex, oldMode    string
+	newPath, newFile, newHex, newMode string
+	info                              string
+}
+
+func parseExternalDiff(args []string) externalDiff {
+	e := externalDiff{
+		path: args[0], oldFile: args[1], oldHex: args[2], oldMode: args[3],
+		newPath: args[0], newFile: args[4], newHex: args[5], newMode: args[6],
+	}
+	if len(args) == 9 {
+		e.newPath, e.info = args[7], args[8]
+	}
+	return e
+}
+
+// rootArgs accepts no arguments, for --file1 and --file2, or the arguments
+// of git difftool (two files) or of a git external diff driver.
+func rootArgs(cmd *cobra.Command, args []string) error {
+	switch len(args) {
+	case 0, 2, 7, 9:
+		return nil
+	case 1:
+		// git passes only the path of an unmerged file, and sets the path
+		// counters for external diff drivers only.
+		if os.Getenv("GIT_DIFF_PATH_TOTAL") != "" {
+			return nil
+		}
+		return fmt.Errorf("unknown command %q for %q", args[0], cmd.CommandPath())
+	}
+	return fmt.Errorf("expected two files to compare, got %d arguments", len(args))
+}
+
+// writeHeader introduces the file the way git diff does, including mode
+// changes and the creation or deletion of the file.
+func (e externalDiff) writeHeader(w io.Writer) {
+	fmt.Fprintf(w, "diff --git a/%s b/%s\n", e.path, e.newPath)
+	switch {
+	case e.oldMode == ".":
+		fmt.Fprintf(w, "new file mode %s\n", e.newMode)
+	case e.newMode == ".":
+		fmt.Fprintf(w, "deleted file mode %s\n", e.oldMode)
+	case e.oldMode != e.newMode:
+		fmt.Fprintf(w, "old mode %s\nnew mode %s\n", e.oldMode, e.newMode)
+	}
+	if e.info != "" {
+		fmt.Fprint(w, strings.TrimSuffix(e.info, "\n")+"\n")
+	}
+}
+
+// unifiedFiles names the sides of the diff as git does, with a/ and b/
+// prefixes and /dev/null for a missing side.
+func (e externalDiff) unifiedFiles() (old, new display.UnifiedFile) {
+	old, new = display.UnifiedFile{Name: "a/" + e.path}, display.UnifiedFile{Name: "b/" + e.newPath}
+	if e.oldMode == "." {
+		old.Name = os.DevNull
+	}
+	if e.newMode == "." {
+		new.Name = os.DevNull
+	}
+	return old, new
+}
+
+// binaryFiles reports whether either file looks binary and, if so, whether
+// they differ. Both files are left rewound.
+func binaryFiles(file1, file2 io.ReadSeeker) (binary, differ bool, err error) {
+	rewind := func() error {
+		for _, f := range []io.ReadSeeker{file1, file2} {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, f := range []io.ReadSeeker{file1, file2} {
+		isBinary, err := diff.IsBinary(f)
+		if err != nil {
+			return false, false, err
+		}
+		binary = binary || isBinary
+	}
+	if err := rewind(); err != nil || !binary {
+		return false, false, err
+	}
+	content1, err := io.ReadAll(file1)
+	if err != nil {
+		return false, false, err
+	}
+	content2, err := io.ReadAll(file2)
+	if err != nil {
+		return false, false, err
+	}
+	return true, !bytes.Equal(content1, content2), rewind()
+}
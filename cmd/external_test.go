@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExternalDiff(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantHeader string
+		wantOld    string
+		wantNew    string
+	}{
+		{
+			name:       "modified",
+			args:       []string{"f.txt", "/tmp/old", "abc123", "100644", "f.txt", "def456", "100644"},
+			wantHeader: "diff --git a/f.txt b/f.txt\n",
+			wantOld:    "a/f.txt",
+			wantNew:    "b/f.txt",
+		},
+		{
+			name:       "new file",
+			args:       []string{"new.txt", os.DevNull, ".", ".", "new.txt", "def456", "100644"},
+			wantHeader: "diff --git a/new.txt b/new.txt\nnew file mode 100644\n",
+			wantOld:    os.DevNull,
+			wantNew:    "b/new.txt",
+		},
+		{
+			name:       "deleted file",
+			args:       []string{"gone.txt", "/tmp/old", "abc123", "100644", os.DevNull, ".", "."},
+			wantHeader: "diff --git a/gone.txt b/gone.txt\ndeleted file mode 100644\n",
+			wantOld:    "a/gone.txt",
+			wantNew:    os.DevNull,
+		},
+		{
+			name:       "mode change",
+			args:       []string{"run.sh", "/tmp/old", "abc123", "100644", "run.sh", "abc123", "100755"},
+			wantHeader: "diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100755\n",
+			wantOld:    "a/run.sh",
+			wantNew:    "b/run.sh",
+		},
+		{
+			name:       "renamed",
+			args:       []string{"old.txt", "/tmp/old", "abc123", "100644", "/tmp/new", "def456", "100644", "new.txt", "similarity index 90%\nrename from old.txt\nrename to new.txt\n"},
+			wantHeader: "diff --git a/old.txt b/new.txt\nsimilarity index 90%\nrename from old.txt\nrename to new.txt\n",
+			wantOld:    "a/old.txt",
+			wantNew:    "b/new.txt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := parseExternalDiff(tt.args)
+			if e.oldFile != tt.args[1] || e.newFile != tt.args[4] {
+				t.Errorf("parseExternalDiff() files = %q, %q, want %q, %q", e.oldFile, e.newFile, tt.args[1], tt.args[4])
+			}
+			var b strings.Builder
+			e.writeHeader(&b)
+			if got := b.String(); got != tt.wantHeader {
+				t.Errorf("writeHeader() = %q, want %q", got, tt.wantHeader)
+			}
+			old, new := e.unifiedFiles()
+			if old.Name != tt.wantOld || new.Name != tt.wantNew {
+				t.Errorf("unifiedFiles() = %q, %q, want %q, %q", old.Name, new.Name, tt.wantOld, tt.wantNew)
+			}
+		})
+	}
+}
+
+func TestRootArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      int
+		pathTotal string
+		wantErr   bool
+	}{
+		{name: "flags only", args: 0},
+		{name: "difftool", args: 2},
+		{name: "external diff", args: 7},
+		{name: "external diff of a rename", args: 9},
+		{name: "unmerged path from git", args: 1, pathTotal: "3"},
+		{name: "unknown command", args: 1, wantErr: true},
+		{name: "three files", args: 3, wantErr: true},
+		{name: "eight arguments", args: 8, pathTotal: "3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GIT_DIFF_PATH_TOTAL", tt.pathTotal)
+			err := rootArgs(rootCmd, make([]string, tt.args))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("rootArgs() with %d arguments error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+	"github.com/san-kum/diff-dance/pkg/display"
+	"github.com/san-kum/diff-dance/pkg/git"
+	"github.com/san-kum/diff-dance/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var gitCmd = &cobra.Command{
+	Use:   "git [REV [REV]] [-- PATH...]",
+	Short: "Compare revisions, the index and the working tree of a git repository",
+	Long: `git compares the files of the git repository in the current directory:
+
+  diff-dance git               the index against the working tree
+  diff-dance git --staged      HEAD against the index
+  diff-dance git REV           REV against the working tree
+  diff-dance git --staged REV  REV against the index
+  diff-dance git REV1 REV2     REV1 against REV2, also written REV1..REV2
+  diff-dance git REV1...REV2   the merge base of REV1 and REV2 against REV2
+
+PATHs after -- limit the comparison to matching files. Every output format
+works as it does for directories; --heatmap, --structural and --wordcloud
+cover all changed files.`,
+	Run: gitDiff,
+}
+
+func init() {
+	gitCmd.Flags().Bool("staged", false, "Compare against the index instead of the working tree")
+	gitCmd.Flags().Bool("cached", false, "Synonym for --staged")
+	gitCmd.Flags().Bool("heatmap", false, "Generate a heatmap visualization")
+	gitCmd.Flags().Bool("wordcloud", false, "Generate a word cloud visualization")
+	gitCmd.Flags().Bool("structural", false, "Show structural changes (for Go files)")
+	gitCmd.Flags().String("format", "terminal", "Output format (terminal, html, unified, json, ndjson, markdown)")
+	addDiffFlags(gitCmd)
+
+	rootCmd.AddCommand(gitCmd)
+}
+
+func gitDiff(cmd *cobra.Command, args []string) {
+	staged, _ := cmd.Flags().GetBool("staged")
+	cached, _ := cmd.Flags().GetBool("cached")
+	heatmap, _ := cmd.Flags().GetBool("heatmap")
+	wordcloud, _ := cmd.Flags().GetBool("wordcloud")
+	structural, _ := cmd.Flags().GetBool("structural")
+	format, _ := cmd.Flags().GetString("format")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	opts, displayOpts := diffOptions(cmd)
+
+	revs, paths := args, []string(nil)
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		revs, paths = args[:dash], args[dash:]
+	}
+
+	repo, err := git.Open(".")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	old, new, err := gitSources(repo, revs, staged || cached)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	dirDiffs, err := gitWithTimeout(repo, old, new, paths, opts, timeout)
+	if err != nil {
+		fmt.Printf("Error diffing revisions: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	switch {
+	case heatmap:
+		err = gitFiles(repo, old, new, dirDiffs, func(d diff.DirectoryDiff, content1, content2 []byte) error {
+			fmt.Printf("~ File: %s\n", d.File1)
+			file1Lines, err := utils.ReadLines(bytes.NewReader(content1))
+			if err != nil {
+				return err
+			}
+			file2Lines, err := utils.ReadLines(bytes.NewReader(content2))
+			if err != nil {
+				return err
+			}
+			display.Heatmap(d.Diffs, file1Lines, file2Lines, os.Stdout)
+			return nil
+		})
+	case structural:
+		var goDiffs []diff.DirectoryDiff
+		for _, d := range dirDiffs {
+			if path.Ext(d.File1) == ".go" {
+				goDiffs = append(goDiffs, d)
+			}
+		}
+		err = gitFiles(repo, old, new, goDiffs, func(d diff.DirectoryDiff, content1, content2 []byte) error {
+			ctx, cancel := timeoutContext(timeout)
+			defer cancel()
+			structuralDiffs, err := diff.StructuralDiffsContext(ctx, bytes.NewReader(content1), bytes.NewReader(content2))
+			if err != nil {
+				return fmt.Errorf("%s: %w", d.File1, err)
+			}
+			fmt.Printf("~ File: %s\n", d.File1)
+			display.Structural(structuralDiffs, os.Stdout)
+			return nil
+		})
+	case wordcloud:
+		var diffs []diff.Diff
+		for _, d := range dirDiffs {
+			diffs = append(diffs, d.Diffs...)
+		}
+		display.WordCloud(diffs, os.Stdout)
+	case format == "html":
+		err = display.HTMLDir(dirDiffs, os.Stdout, displayOpts)
+	case format == "unified":
+		err = display.UnifiedDir(dirDiffs, "a", "b", os.Stdout, displayOpts)
+	case format == "json":
+		err = display.JSONDir(dirDiffs, old.Name(), new.Name(), os.Stdout, false, displayOpts)
+	case format == "ndjson":
+		var out *display.NDJSONWriter
+		if out, err = display.NewNDJSONWriter(os.Stdout, old.Name(), new.Name(), displayOpts); err != nil {
+			break
+		}
+		for _, d := range dirDiffs {
+			if err = out.Write(d); err != nil {
+				break
+			}
+		}
+	case format == "markdown":
+		structuralDiffs := map[string][]diff.StructuralDiff{}
+		err = gitFiles(repo, old, new, dirDiffs, func(d diff.DirectoryDiff, content1, content2 []byte) error {
+			if path.Ext(d.File1) == ".go" {
+				structuralDiffs[d.File1] = markdownStructural(content1, content2, timeout)
+			}
+			return nil
+		})
+		if err == nil {
+			err = display.MarkdownDir(dirDiffs, structuralDiffs, os.Stdout, displayOpts)
+		}
+	default:
+		display.TerminalDir(dirDiffs, os.Stdout, displayOpts)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+// gitSources works out what to compare from the revisions given, the way
+// git diff does.
+func gitSources(repo *git.Repo, revs []string, staged bool) (old, new git.Source, err error) {
+	if len(revs) == 1 {
+		if before, after, ok := strings.Cut(revs[0], "..."); ok {
+			old, new = git.Source(orHead(before)), git.Source(orHead(after))
+			base, err := repo.MergeBase(context.Background(), old, new)
+			return base, new, err
+		}
+		if before, after, ok := strings.Cut(revs[0], ".."); ok {
+			return git.Source(orHead(before)), git.Source(orHead(after)), nil
+		}
+	}
+	switch {
+	case len(revs) > 2:
+		return "", "", errors.New("at most two revisions may be given")
+	case len(revs) == 2:
+		if staged {
+			return "", "", errors.New("--staged compares a single revision with the index")
+		}
+		return git.Source(revs[0]), git.Source(revs[1]), nil
+	case staged && len(revs) == 1:
+		return git.Source(revs[0]), git.Index, nil
+	case staged:
+		return "HEAD", git.Index, nil
+	case len(revs) == 1:
+		return git.Source(revs[0]), git.WorkTree, nil
+	default:
+		return git.Index, git.WorkTree, nil
+	}
+}
+
+// orHead stands in HEAD for a missing side of a range, as git does.
+func orHead(rev string) string {
+	if rev == "" {
+		return "HEAD"
+	}
+	return rev
+}
+
+// gitFiles calls show for every changed text file with the contents of
+// both versions.
+func gitFiles(repo *git.Repo, old, new git.Source, dirDiffs []diff.DirectoryDiff, show func(d diff.DirectoryDiff, content1, content2 []byte) error) error {
+	ctx := context.Background()
+	for _, d := range dirDiffs {
+		if d.Type != "change" || d.BinaryDiff {
+			continue
+		}
+		content1, err := repo.ReadFile(ctx, old, d.File1)
+		if err != nil {
+			return err
+		}
+		content2, err := repo.ReadFile(ctx, new, d.File2)
+		if err != nil {
+			return err
+		}
+		if err := show(d, content1, content2); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gitWithTimeout is filesWithTimeout for git sources.
+func gitWithTimeout(repo *git.Repo, old, new git.Source, paths []string, opts diff.Options, timeout time.Duration) ([]diff.DirectoryDiff, error) {
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
+	diffs, err := repo.Diff(ctx, old, new, paths, opts)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return diffs, err
+	}
+	fmt.Fprintf(os.Stderr, "No minimal diff within %v, showing an approximate one\n", timeout)
+	opts.MaxCost = diff.HeuristicMaxCost
+	return repo.Diff(context.Background(), old, new, paths, opts)
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+	"github.com/san-kum/diff-dance/pkg/display"
+	"github.com/spf13/cobra"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge BASE OURS THEIRS",
+	Short: "Merge the changes two files made to a common ancestor",
+	Long: `merge combines the changes OURS and THEIRS made to BASE and writes the result
+to stdout or --output. Regions both sides changed differently are written
+between git-style conflict markers; --diff3 also shows the BASE version.
+
+With --interactive the conflicts are resolved one by one in a terminal UI
+and the result is saved to --output, or over OURS when it is not given. To
+use it as git mergetool:
+
+  git config mergetool.diff-dance.cmd 'diff-dance merge --interactive "$BASE" "$LOCAL" "$REMOTE" -o "$MERGED"'
+  git config mergetool.diff-dance.trustExitCode true
+
+The exit status is 0 for a clean merge, 1 when conflicts remain and 2 on
+errors. In interactive mode it is 0 only when the result was saved with
+every conflict resolved.`,
+	Args: cobra.ExactArgs(3),
+	Run:  mergeFiles,
+}
+
+func init() {
+	mergeCmd.Flags().StringP("output", "o", "", "Write the merged result to this file instead of stdout")
+	mergeCmd.Flags().Bool("diff3", false, "Show the base version of conflicting regions")
+	mergeCmd.Flags().StringArrayP("label", "L", nil, "Label for the conflict markers, given up to three times for OURS, BASE and THEIRS (default: the file names)")
+	mergeCmd.Flags().Bool("interactive", false, "Resolve conflicts interactively in a terminal UI")
+	mergeCmd.Flags().String("algorithm", "myers", "Diff algorithm (myers, patience, histogram)")
+
+	rootCmd.AddCommand(mergeCmd)
+}
+
+func mergeFiles(cmd *cobra.Command, args []string) {
+	output, _ := cmd.Flags().GetString("output")
+	diff3, _ := cmd.Flags().GetBool("diff3")
+	labels, _ := cmd.Flags().GetStringArray("label")
+	algorithm, _ := cmd.Flags().GetString("algorithm")
+	interactive, _ := cmd.Flags().GetBool("interactive")
+
+	differ, err := diff.NewDiffer(algorithm)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if len(labels) > 3 {
+		fmt.Fprintln(os.Stderr, "At most three --label flags may be given.")
+		os.Exit(2)
+	}
+
+	var inputs [3]io.Reader
+	for i, path := range args {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", path, err)
+			os.Exit(2)
+		}
+		defer f.Close()
+		inputs[i] = f
+	}
+	m, err := diff.Merge3(inputs[0], inputs[1], inputs[2], diff.Options{Algorithm: differ})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error merging files: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Labels are given in diff3 order: ours, base, theirs.
+	names := []string{args[1], args[0], args[2]}
+	copy(names, labels)
+	style := diff.MergeStyle{OursLabel: names[0], BaseLabel: names[1], TheirsLabel: names[2], Diff3: diff3}
+
+	if interactive {
+		if output == "" {
+			output = args[1]
+		}
+		resolved, err := display.InteractiveMerge(m, output, style)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error running application: %v\n", err)
+			os.Exit(2)
+		}
+		if !resolved {
+			os.Exit(1)
+		}
+		return
+	}
+
+	var merged bytes.Buffer
+	diff.WriteMerge(&merged, m, style)
+	if output == "" {
+		_, err = os.Stdout.Write(merged.Bytes())
+	} else {
+		err = os.WriteFile(output, merged.Bytes(), 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing merge: %v\n", err)
+		os.Exit(2)
+	}
+
+	if n := m.Conflicts(); n > 0 {
+		fmt.Fprintf(os.Stderr, "%d conflict(s) remain.\n", n)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+	"github.com/san-kum/diff-dance/pkg/display"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// addDiffFlags defines the flags that control how files are compared and
+// shown, shared by the commands that diff files.
+func addDiffFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("timeout", 0, "Fall back to a faster, approximate diff when a minimal one takes longer than this (0 waits forever)")
+	cmd.Flags().String("algorithm", "myers", "Diff algorithm (myers, patience, histogram)")
+	cmd.Flags().IntP("context", "U", 3, "Number of unchanged lines shown around each change")
+	cmd.Flags().String("intraline", "word", "Highlight changes within lines (word, char, none)")
+	cmd.Flags().Int("moves", 3, "Minimum non-blank lines for a block to be shown as moved (0 disables move detection)")
+	cmd.Flags().BoolP("side-by-side", "y", false, "Show old and new lines in two columns")
+	cmd.Flags().IntP("width", "W", 0, "Width of the side-by-side output (default: the terminal width, or 80)")
+	cmd.Flags().Int("tabsize", 8, "Tab stop distance used to expand tabs in side-by-side output")
+	cmd.Flags().Bool("wrap", false, "Wrap side-by-side lines that do not fit instead of cutting them off")
+	cmd.Flags().String("template", "", "Directory of *.html templates overriding those of the HTML output")
+	cmd.Flags().String("theme", "light", "Colors of the HTML output ("+strings.Join(display.HTMLThemes, ", ")+", or a CSS file)")
+	cmd.Flags().Int("max-lines", 200, "Diff lines shown for each file in Markdown output before cutting it off (0 shows all)")
+	cmd.Flags().BoolP("ignore-all-space", "w", false, "Ignore all white space")
+	cmd.Flags().BoolP("ignore-space-change", "b", false, "Ignore changes in the amount of white space")
+	cmd.Flags().BoolP("ignore-trailing-space", "Z", false, "Ignore white space at line end")
+	cmd.Flags().BoolP("ignore-blank-lines", "B", false, "Ignore changes that only add or remove blank lines")
+	cmd.Flags().Bool("strip-trailing-cr", false, "Ignore carriage returns at line end")
+	cmd.Flags().StringArrayP("ignore-matching", "I", nil, "Treat lines matching this regular expression as equal (repeatable)")
+	cmd.Flags().BoolP("ignore-case", "i", false, "Ignore case differences")
+}
+
+// diffOptions reads the flags defined by addDiffFlags, exiting when they
+// are invalid.
+func diffOptions(cmd *cobra.Command) (diff.Options, display.Options) {
+	algorithm, _ := cmd.Flags().GetString("algorithm")
+	context, _ := cmd.Flags().GetInt("context")
+	intraline, _ := cmd.Flags().GetString("intraline")
+	moves, _ := cmd.Flags().GetInt("moves")
+	sideBySide, _ := cmd.Flags().GetBool("side-by-side")
+	width, _ := cmd.Flags().GetInt("width")
+	tabSize, _ := cmd.Flags().GetInt("tabsize")
+	wrap, _ := cmd.Flags().GetBool("wrap")
+	htmlTemplate, _ := cmd.Flags().GetString("template")
+	theme, _ := cmd.Flags().GetString("theme")
+	maxLines, _ := cmd.Flags().GetInt("max-lines")
+	ignoreAllSpace, _ := cmd.Flags().GetBool("ignore-all-space")
+	ignoreSpaceChange, _ := cmd.Flags().GetBool("ignore-space-change")
+	ignoreTrailingSpace, _ := cmd.Flags().GetBool("ignore-trailing-space")
+	ignoreBlankLines, _ := cmd.Flags().GetBool("ignore-blank-lines")
+	stripTrailingCR, _ := cmd.Flags().GetBool("strip-trailing-cr")
+	ignoreMatching, _ := cmd.Flags().GetStringArray("ignore-matching")
+	ignoreCase, _ := cmd.Flags().GetBool("ignore-case")
+
+	differ, err := diff.NewDiffer(algorithm)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	opts := diff.Options{
+		Algorithm:           differ,
+		IgnoreAllSpace:      ignoreAllSpace,
+		IgnoreSpaceChange:   ignoreSpaceChange,
+		IgnoreTrailingSpace: ignoreTrailingSpace,
+		IgnoreBlankLines:    ignoreBlankLines,
+		StripTrailingCR:     stripTrailingCR,
+		IgnoreCase:          ignoreCase,
+		Context:             context,
+	}
+	for _, expr := range ignoreMatching {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			fmt.Printf("Invalid --ignore-matching expression: %v\n", err)
+			os.Exit(exitError)
+		}
+		opts.IgnoreMatching = append(opts.IgnoreMatching, re)
+	}
+	if width <= 0 {
+		width = terminalWidth()
+	}
+	return opts, display.Options{
+		Context:    context,
+		IntraLine:  intraline,
+		MoveLines:  moves,
+		SideBySide: sideBySide,
+		Width:      width,
+		TabSize:    tabSize,
+		Wrap:       wrap,
+		Template:   htmlTemplate,
+		Theme:      theme,
+		MaxLines:   maxLines,
+	}
+}
+
+// terminalWidth is the width of the terminal stdout is connected to, or of
+// the one $COLUMNS describes, and zero when neither is known.
+func terminalWidth() int {
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	width, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	return width
+}
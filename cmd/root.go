@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"time"
 
 	"github.com/san-kum/diff-dance/pkg/diff"
 	"github.com/san-kum/diff-dance/pkg/display"
@@ -12,18 +17,46 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Exit statuses of the root command, as GNU diff uses them.
+const (
+	exitSame   = 0
+	exitDiffer = 1
+	exitError  = 2
+)
+
 var rootCmd = &cobra.Command{
-	Use:   "diff-dance",
+	Use:   "diff-dance [OLD NEW]",
 	Short: "Visualize file and directory differences creatively",
 	Long: `diff-dance provides various ways to visualize differences
-between files and directories, going beyond traditional line-by-line diffs.`,
-	Run: diffDance,
+between files and directories, going beyond traditional line-by-line diffs.
+
+The files to compare are given with --file1 and --file2 or as arguments.
+diff-dance also understands the arguments git passes to an external diff
+driver, so git can show its diffs through it:
+
+  git config diff.external 'diff-dance --format unified'
+  git config difftool.diff-dance.cmd 'diff-dance "$LOCAL" "$REMOTE"'
+
+The exit status is 0 when the inputs are the same, 1 when they differ and 2
+on errors, except as an external diff driver, where it is 0 unless an error
+occurs because git stops at the first driver reporting a failure.
+
+HTML reports take the colors of --theme, which also accepts a CSS file to
+match a site's style. The *.html files in a --template directory replace
+the built-in templates, file.html, dir.html and layout.html, whole or one
+{{define}} block at a time, such as "head" or "toolbar".
+
+Markdown reports are meant for pull request comments: a table of the
+changed files, their diffs cut off after --max-lines lines, and the changes
+to the functions of Go files.`,
+	Args: rootArgs,
+	Run:  diffDance,
 }
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 }
 
@@ -34,10 +67,11 @@ func init() {
 	rootCmd.Flags().Bool("wordcloud", false, "Generate a word cloud visualization")
 	rootCmd.Flags().Bool("structural", false, "Show structural changes (for code)")
 	rootCmd.Flags().Bool("interactive", false, "Enable interactive navigation")
-	rootCmd.Flags().String("format", "terminal", "Output format (terminal, html)")
-
-	rootCmd.MarkFlagRequired("file1")
-	rootCmd.MarkFlagRequired("file2")
+	rootCmd.Flags().String("format", "terminal", "Output format (terminal, html, unified, json, ndjson, markdown)")
+	rootCmd.Flags().Bool("quiet", false, "Only report through the exit status whether the inputs differ")
+	rootCmd.Flags().BoolP("brief", "q", false, "Only report which files differ")
+	rootCmd.Flags().Bool("stream", false, "Diff files in bounded memory, writing output as it is found (terminal and unified formats)")
+	addDiffFlags(rootCmd)
 }
 func diffDance(cmd *cobra.Command, args []string) {
 	file1Path, _ := cmd.Flags().GetString("file1")
@@ -47,104 +81,276 @@ func diffDance(cmd *cobra.Command, args []string) {
 	structural, _ := cmd.Flags().GetBool("structural")
 	interactive, _ := cmd.Flags().GetBool("interactive")
 	format, _ := cmd.Flags().GetString("format")
+	stream, _ := cmd.Flags().GetBool("stream")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	brief, _ := cmd.Flags().GetBool("brief")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	opts, displayOpts := diffOptions(cmd)
+
+	var external *externalDiff
+	switch len(args) {
+	case 1:
+		fmt.Printf("* Unmerged path %s\n", args[0])
+		return
+	case 2:
+		file1Path, file2Path = args[0], args[1]
+	case 7, 9:
+		e := parseExternalDiff(args)
+		external = &e
+		file1Path, file2Path = e.oldFile, e.newFile
+	}
+	// exitDiffering ends the command with the status for whether the inputs
+	// differ.
+	exitDiffering := func(differ bool) {
+		if differ && external == nil {
+			os.Exit(exitDiffer)
+		}
+		os.Exit(exitSame)
+	}
+
+	if len(args) > 0 && (cmd.Flags().Changed("file1") || cmd.Flags().Changed("file2")) {
+		fmt.Println("Give the files to compare either as arguments or with --file1 and --file2, not both.")
+		os.Exit(exitError)
+	}
+	if file1Path == "" || file2Path == "" {
+		fmt.Println("Give the files to compare as arguments or with --file1 and --file2.")
+		os.Exit(exitError)
+	}
+
+	if file1Path == "-" && file2Path == "-" {
+		fmt.Println("Only one side can be read from stdin.")
+		os.Exit(exitError)
+	}
 
 	// Check if paths are directories.
-	info1, err := os.Stat(file1Path)
+	info1, err := statInput(file1Path)
 	if err != nil {
 		fmt.Printf("Error stating file1: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
-	info2, err := os.Stat(file2Path)
+	info2, err := statInput(file2Path)
 	if err != nil {
 		fmt.Printf("Error stating file2: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	// Handle directory diffs.
 	if info1.IsDir() && info2.IsDir() {
-		dirDiffs, err := diff.DirectoryDiffs(file1Path, file2Path) // Call DirectoryDiffs
+		if quiet {
+			differ, err := diff.DirectoriesDiffer(context.Background(), file1Path, file2Path, opts)
+			if err != nil {
+				fmt.Printf("Error diffing directories: %v\n", err)
+				os.Exit(exitError)
+			}
+			exitDiffering(differ)
+		}
+		if brief {
+			dirDiffs, err := diff.BriefDirectoryDiffs(context.Background(), file1Path, file2Path, opts)
+			if err == nil {
+				err = display.BriefDir(dirDiffs, file1Path, file2Path, os.Stdout)
+			}
+			if err != nil {
+				fmt.Printf("Error diffing directories: %v\n", err)
+				os.Exit(exitError)
+			}
+			exitDiffering(dirsDiffer(dirDiffs))
+		}
+
+		if format == "ndjson" && !interactive {
+			differ, err := streamDirs(file1Path, file2Path, opts, displayOpts, timeout)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error diffing directories: %v\n", err)
+				os.Exit(exitError)
+			}
+			exitDiffering(differ)
+		}
+
+		dirDiffs, err := dirsWithTimeout(file1Path, file2Path, opts, timeout)
 		if err != nil {
 			fmt.Printf("Error diffing directories: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitError)
 		}
 
 		// Handle the format for directories
 		switch {
 		case interactive: //If interactive
 			// Interactive mode for directory diffs not supported yet
-			fmt.Fprintf(os.Stderr, "Interactive mode for directories is not implemented yet")
+			fmt.Fprintln(os.Stderr, "Interactive mode for directories is not implemented yet")
+			os.Exit(exitError)
 		case format == "html": //If HTML
-			err = display.HTMLDir(dirDiffs, os.Stdout)
+			err = display.HTMLDir(dirDiffs, os.Stdout, displayOpts)
 			if err != nil {
-				fmt.Printf("Error displaying HTML: %v", err)
+				fmt.Printf("Error displaying HTML: %v\n", err)
+				os.Exit(exitError)
+			}
+		case format == "unified":
+			err = display.UnifiedDir(dirDiffs, file1Path, file2Path, os.Stdout, displayOpts)
+			if err != nil {
+				fmt.Printf("Error writing unified diff: %v\n", err)
+				os.Exit(exitError)
+			}
+		case format == "json":
+			if err := display.JSONDir(dirDiffs, file1Path, file2Path, os.Stdout, false, displayOpts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+				os.Exit(exitError)
+			}
+		case format == "markdown":
+			structuralDiffs := map[string][]diff.StructuralDiff{}
+			for _, d := range dirDiffs {
+				if d.Type != "change" || d.BinaryDiff || filepath.Ext(d.File1) != ".go" {
+					continue
+				}
+				content1, err1 := os.ReadFile(filepath.Join(file1Path, d.File1))
+				content2, err2 := os.ReadFile(filepath.Join(file2Path, d.File2))
+				if err := errors.Join(err1, err2); err != nil {
+					fmt.Fprintf(os.Stderr, "Error reading files: %v\n", err)
+					os.Exit(exitError)
+				}
+				structuralDiffs[d.File1] = markdownStructural(content1, content2, timeout)
+			}
+			if err := display.MarkdownDir(dirDiffs, structuralDiffs, os.Stdout, displayOpts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing Markdown: %v\n", err)
+				os.Exit(exitError)
 			}
 		default: //Terminal
-			display.TerminalDir(dirDiffs, os.Stdout)
+			display.TerminalDir(dirDiffs, os.Stdout, displayOpts)
 
 		}
-		return // Important: Return after handling directory diff
+		exitDiffering(dirsDiffer(dirDiffs)) // Important: stop after handling directory diff
 	} else if info1.IsDir() || info2.IsDir() { // One is file, the other is directory
 		fmt.Println("Cannot compare a file with a directory.")
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	// --- Handle file diffs (existing logic) ---
-	file1, err := os.Open(file1Path)
+	file1, err := openInput(file1Path)
 	if err != nil {
-		fmt.Printf("Error opening file1: %v\n")
-		os.Exit(1)
+		fmt.Printf("Error opening file1: %v\n", err)
+		os.Exit(exitError)
 	}
 	defer file1.Close()
 
-	file2, err := os.Open(file2Path)
+	file2, err := openInput(file2Path)
 	if err != nil {
 		fmt.Printf("Error opening file2: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 	defer file2.Close()
 
-	// We seek, so we can use the files multiple times
-	file1.Seek(0, 0)
-	file2.Seek(0, 0)
-	diffs, err := diff.Files(file1, file2) // Calculate diffs
+	old := display.UnifiedFile{Name: file1Path, ModTime: inputModTime(info1)}
+	new := display.UnifiedFile{Name: file2Path, ModTime: inputModTime(info2)}
+	if external != nil {
+		old, new = external.unifiedFiles()
+		if !interactive && (format == "terminal" || format == "unified") {
+			external.writeHeader(os.Stdout)
+		}
+		binary, differ, err := binaryFiles(file1, file2)
+		if err != nil {
+			fmt.Printf("Error reading files: %v\n", err)
+			os.Exit(exitError)
+		}
+		if binary {
+			if differ && !quiet {
+				fmt.Printf("Binary files %s and %s differ\n", old.Name, new.Name)
+			}
+			exitDiffering(differ)
+		}
+	}
+
+	if quiet || brief {
+		differ, err := diff.FilesDiffer(file1, file2, opts)
+		if err != nil {
+			fmt.Printf("Error diffing files: %v\n", err)
+			os.Exit(exitError)
+		}
+		if differ && brief {
+			fmt.Printf("Files %s and %s differ\n", old.Name, new.Name)
+		}
+		exitDiffering(differ)
+	}
+
+	if stream {
+		if heatmap || wordcloud || structural || interactive || (format != "terminal" && format != "unified") {
+			fmt.Println("--stream only supports the terminal and unified formats.")
+			os.Exit(exitError)
+		}
+		// Both renderers only write anything for differences. As with
+		// streamDirs, running out of time after output has been written
+		// is an error.
+		ctx, cancel := timeoutContext(timeout)
+		defer cancel()
+		out := &countingWriter{w: os.Stdout}
+		if format == "unified" {
+			err = display.UnifiedStream(ctx, file1, file2, old, new, out, opts, displayOpts)
+		} else {
+			err = display.TerminalStream(ctx, file1, file2, out, opts, displayOpts)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error diffing files: %v\n", err)
+			os.Exit(exitError)
+		}
+		exitDiffering(out.n > 0)
+	}
+
+	// Pipes and stdin can only be read once, so keep what they hold for the
+	// renderers that need the input again.
+	content1, err := io.ReadAll(file1)
+	if err != nil {
+		fmt.Printf("Error reading file1: %v\n", err)
+		os.Exit(exitError)
+	}
+	content2, err := io.ReadAll(file2)
+	if err != nil {
+		fmt.Printf("Error reading file2: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	diffs, err := filesWithTimeout(bytes.NewReader(content1), bytes.NewReader(content2), opts, timeout)
 	if err != nil {
 		fmt.Printf("Error diffing files: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	switch {
 	case heatmap:
-		// Read lines from files *before* calculating diffs (for heatmap).
-		file1Lines, err := utils.ReadLines(file1)
+		file1Lines, err := utils.ReadLines(bytes.NewReader(content1))
 		if err != nil {
 			fmt.Printf("Error reading lines from file1: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitError)
 		}
-		file2Lines, err := utils.ReadLines(file2)
+		file2Lines, err := utils.ReadLines(bytes.NewReader(content2))
 		if err != nil {
 			fmt.Printf("Error reading lines from file2: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitError)
 		}
-		//We reset the file pointers to calculate diffs again.
-		file1.Seek(0, 0)
-		file2.Seek(0, 0)
 		display.Heatmap(diffs, file1Lines, file2Lines, os.Stdout)
 	case wordcloud:
 		display.WordCloud(diffs, os.Stdout)
 	case structural:
-		if filepath.Ext(file1Path) == ".go" && filepath.Ext(file2Path) == ".go" {
-			structuralDiffs, err := diff.StructuralDiffs(file1, file2)
+		if goInputs(file1Path, file2Path) {
+			ctx, cancel := timeoutContext(timeout)
+			defer cancel()
+			structuralDiffs, err := diff.StructuralDiffsContext(ctx, bytes.NewReader(content1), bytes.NewReader(content2))
 			if err != nil {
 				fmt.Printf("Error calculating structural diff: %v\n", err)
-				os.Exit(1)
+				os.Exit(exitError)
+			}
+			if format == "json" || format == "ndjson" {
+				err = display.JSONStructuralDiffs(structuralDiffs, os.Stdout, format == "ndjson")
+			} else {
+				display.Structural(structuralDiffs, os.Stdout)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+				os.Exit(exitError)
 			}
-			display.Structural(structuralDiffs, os.Stdout)
-
 		} else {
 			fmt.Println("Structural diff is only supported for Go files (.go).")
+			os.Exit(exitError)
 		}
 	case interactive:
-		display.Interactive(file1Path, file2Path) // Pass file *paths*
+		display.Interactive(bytes.NewReader(content1), bytes.NewReader(content2), opts, displayOpts)
 
 	default: //Handle format here, so we print in terminal or HTML
 		if format == "html" {
@@ -159,17 +365,167 @@ func diffDance(cmd *cobra.Command, args []string) {
 				}
 			}
 			if searchRegex != nil { // If we have search
-				err = display.HTMLWithHighlight(diffs, os.Stdout, searchRegex)
+				err = display.HTMLWithHighlight(diffs, os.Stdout, searchRegex, displayOpts)
 			} else {
-				err = display.HTML(diffs, os.Stdout) // Use standard output
+				err = display.HTML(diffs, os.Stdout, displayOpts) // Use standard output
 			}
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error generating HTML: %v\n", err) //Error
-				os.Exit(1)
+				os.Exit(exitError)
+			}
+		} else if format == "unified" {
+			if err := display.Unified(diffs, old, new, os.Stdout, displayOpts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing unified diff: %v\n", err)
+				os.Exit(exitError)
+			}
+		} else if format == "json" || format == "ndjson" {
+			if err := display.JSON(diffs, old.Name, new.Name, os.Stdout, format == "ndjson", displayOpts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+				os.Exit(exitError)
+			}
+		} else if format == "markdown" {
+			var structuralDiffs []diff.StructuralDiff
+			if goInputs(file1Path, file2Path) {
+				structuralDiffs = markdownStructural(content1, content2, timeout)
+			}
+			if err := display.Markdown(diffs, structuralDiffs, old.Name, new.Name, os.Stdout, displayOpts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing Markdown: %v\n", err)
+				os.Exit(exitError)
 			}
 		} else {
-			display.Terminal(diffs, os.Stdout) //Use standard output
+			display.Terminal(diffs, os.Stdout, displayOpts) //Use standard output
 		}
 	}
+	exitDiffering(diff.HasChanges(diffs))
+}
+
+// timeoutContext returns a context that expires after timeout, or never
+// when timeout is zero.
+func timeoutContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// filesWithTimeout diffs two files, settling for an approximate diff when a
+// minimal one takes longer than timeout.
+func filesWithTimeout(file1, file2 io.ReadSeeker, opts diff.Options, timeout time.Duration) ([]diff.Diff, error) {
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
+	diffs, err := diff.FilesContext(ctx, file1, file2, opts)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return diffs, err
+	}
+	fmt.Fprintf(os.Stderr, "No minimal diff within %v, showing an approximate one\n", timeout)
+	if _, err := file1.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := file2.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	opts.MaxCost = diff.HeuristicMaxCost
+	return diff.Files(file1, file2, opts)
+}
+
+// dirsWithTimeout is filesWithTimeout for directories.
+func dirsWithTimeout(dir1, dir2 string, opts diff.Options, timeout time.Duration) ([]diff.DirectoryDiff, error) {
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
+	diffs, err := diff.DirectoryDiffsContext(ctx, dir1, dir2, opts)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return diffs, err
+	}
+	fmt.Fprintf(os.Stderr, "No minimal diff within %v, showing an approximate one\n", timeout)
+	opts.MaxCost = diff.HeuristicMaxCost
+	return diff.DirectoryDiffs(dir1, dir2, opts)
 }
 
+// streamDirs writes the diff of two directories as NDJSON while walking
+// them, so that no more than one file's diff is held at a time, and
+// reports whether they differ. As output has already been written by then,
+// running out of time is an error rather than a reason to start over with
+// an approximate diff.
+func streamDirs(dir1, dir2 string, opts diff.Options, displayOpts display.Options, timeout time.Duration) (bool, error) {
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
+	out, err := display.NewNDJSONWriter(os.Stdout, dir1, dir2, displayOpts)
+	if err != nil {
+		return false, err
+	}
+	differ := false
+	err = diff.WalkDirectoryDiffs(ctx, dir1, dir2, opts, func(d diff.DirectoryDiff) error {
+		differ = differ || dirsDiffer([]diff.DirectoryDiff{d})
+		return out.Write(d)
+	})
+	return differ, err
+}
+
+// statInput is os.Stat, with "-" standing for stdin.
+func statInput(path string) (os.FileInfo, error) {
+	if path == "-" {
+		return os.Stdin.Stat()
+	}
+	return os.Stat(path)
+}
+
+// openInput is os.Open, with "-" standing for stdin.
+func openInput(path string) (*os.File, error) {
+	if path == "-" {
+		return os.Stdin, nil
+	}
+	return os.Open(path)
+}
+
+// inputModTime is the modification time of a regular file. Pipes and
+// terminals have none worth showing.
+func inputModTime(info os.FileInfo) time.Time {
+	if !info.Mode().IsRegular() {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// goInputs reports whether two inputs can be compared as Go source: at
+// least one is a .go file and the other is too or, like stdin and pipes,
+// has no extension to go by.
+func goInputs(path1, path2 string) bool {
+	ext1, ext2 := filepath.Ext(path1), filepath.Ext(path2)
+	return (ext1 == ".go" || ext1 == "") && (ext2 == ".go" || ext2 == "") && (ext1 == ".go" || ext2 == ".go")
+}
+
+// markdownStructural returns the changes to the functions of two versions
+// of a Go file for the Markdown output, which leaves out files that do not
+// parse rather than failing the whole report.
+func markdownStructural(content1, content2 []byte, timeout time.Duration) []diff.StructuralDiff {
+	ctx, cancel := timeoutContext(timeout)
+	defer cancel()
+	structuralDiffs, err := diff.StructuralDiffsContext(ctx, bytes.NewReader(content1), bytes.NewReader(content2))
+	if err != nil {
+		return nil
+	}
+	return structuralDiffs
+}
+
+// dirsDiffer reports whether a directory diff found any added, removed or
+// changed entry.
+func dirsDiffer(diffs []diff.DirectoryDiff) bool {
+	for _, d := range diffs {
+		if d.Type != "same" && d.Type != "same_dir" {
+			return true
+		}
+	}
+	return false
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
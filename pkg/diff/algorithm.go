@@ -0,0 +1,266 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Differ computes an edit script turning lines a into lines b. The result
+// must carry OldLine and NewLine for every entry.
+type Differ interface {
+	Diff(a, b []string) []Diff
+}
+
+// Myers produces a minimal edit script. It is the default algorithm.
+type Myers struct{}
+
+func (Myers) Diff(a, b []string) []Diff {
+	return numberLines(myers(a, b))
+}
+
+func (Myers) diffWithin(s *search, a, b []string) []Diff {
+	return numberLines(s.myers(a, b))
+}
+
+// Patience anchors the diff on lines that occur exactly once in both inputs,
+// which keeps unrelated lines such as lone braces from being matched up.
+type Patience struct{}
+
+func (Patience) Diff(a, b []string) []Diff {
+	return Patience{}.diffWithin(newSearch(context.Background(), 0), a, b)
+}
+
+func (Patience) diffWithin(s *search, a, b []string) []Diff {
+	return numberLines(s.patience(a, b))
+}
+
+// Histogram anchors the diff on the least frequent common lines, extending
+// each anchor into the longest common region around it. It behaves like
+// Patience on unique lines but degrades more gracefully when there are none.
+type Histogram struct{}
+
+func (Histogram) Diff(a, b []string) []Diff {
+	return Histogram{}.diffWithin(newSearch(context.Background(), 0), a, b)
+}
+
+func (Histogram) diffWithin(s *search, a, b []string) []Diff {
+	return numberLines(s.histogram(a, b))
+}
+
+// limitedDiffer is implemented by the built-in algorithms, which stop when
+// the search is cancelled and honor its cost budget.
+type limitedDiffer interface {
+	diffWithin(s *search, a, b []string) []Diff
+}
+
+// search carries the limits of one diff computation. Once ctx is done err
+// is set and the algorithms wind down, returning a valid but meaningless
+// script.
+type search struct {
+	ctx context.Context
+	// maxCost is the edit distance after which Myers settles for a
+	// non-minimal script; zero means no limit.
+	maxCost int
+	err     error
+}
+
+func newSearch(ctx context.Context, maxCost int) *search {
+	return &search{ctx: ctx, maxCost: maxCost}
+}
+
+func (s *search) cancelled() bool {
+	if s.err == nil {
+		s.err = s.ctx.Err()
+	}
+	return s.err != nil
+}
+
+// changedLines is the script that removes all of a and adds all of b.
+func changedLines(a, b []string) []Diff {
+	diffs := make([]Diff, 0, len(a)+len(b))
+	for _, line := range a {
+		diffs = append(diffs, Diff{Line: line, Type: "remove"})
+	}
+	for _, line := range b {
+		diffs = append(diffs, Diff{Line: line, Type: "add"})
+	}
+	return diffs
+}
+
+// Algorithms lists the names accepted by NewDiffer.
+var Algorithms = []string{"myers", "patience", "histogram"}
+
+// NewDiffer returns the algorithm registered under name.
+func NewDiffer(name string) (Differ, error) {
+	switch name {
+	case "", "myers":
+		return Myers{}, nil
+	case "patience":
+		return Patience{}, nil
+	case "histogram":
+		return Histogram{}, nil
+	}
+	return nil, fmt.Errorf("unknown diff algorithm %q (want one of %v)", name, Algorithms)
+}
+
+func sameLines(lines []string) []Diff {
+	diffs := make([]Diff, len(lines))
+	for i, line := range lines {
+		diffs[i] = Diff{Line: line, Type: "same"}
+	}
+	return diffs
+}
+
+// withCommonEnds strips the common prefix and suffix of a and b, hands the
+// middle to inner and glues the pieces back together.
+func withCommonEnds(a, b []string, inner func(a, b []string) []Diff) []Diff {
+	prefix, suffix := commonEnds(a, b)
+	diffs := sameLines(a[:prefix])
+	diffs = append(diffs, inner(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	return append(diffs, sameLines(a[len(a)-suffix:])...)
+}
+
+func (s *search) patience(a, b []string) []Diff {
+	return withCommonEnds(a, b, func(a, b []string) []Diff {
+		if len(a) == 0 || len(b) == 0 || s.cancelled() {
+			return s.shortestEdit(a, b)
+		}
+
+		anchors := uniqueAnchors(a, b)
+		if len(anchors) == 0 {
+			return s.shortestEdit(a, b)
+		}
+
+		var diffs []Diff
+		i, j := 0, 0
+		for _, anchor := range anchors {
+			diffs = append(diffs, s.patience(a[i:anchor.a], b[j:anchor.b])...)
+			diffs = append(diffs, Diff{Line: a[anchor.a], Type: "same"})
+			i, j = anchor.a+1, anchor.b+1
+		}
+		return append(diffs, s.patience(a[i:], b[j:])...)
+	})
+}
+
+type anchor struct {
+	a, b int
+}
+
+// uniqueAnchors returns the longest increasing sequence of line pairs that
+// occur exactly once in both a and b, found with patience sorting.
+func uniqueAnchors(a, b []string) []anchor {
+	type occurrence struct {
+		countA, countB int
+		indexA, indexB int
+	}
+	seen := make(map[string]*occurrence)
+	for i, line := range a {
+		o, ok := seen[line]
+		if !ok {
+			o = &occurrence{}
+			seen[line] = o
+		}
+		o.countA++
+		o.indexA = i
+	}
+	for j, line := range b {
+		if o, ok := seen[line]; ok {
+			o.countB++
+			o.indexB = j
+		}
+	}
+
+	var candidates []anchor
+	for _, o := range seen {
+		if o.countA == 1 && o.countB == 1 {
+			candidates = append(candidates, anchor{a: o.indexA, b: o.indexB})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].a < candidates[j].a
+	})
+
+	// Patience sorting: piles[k] is the index of the candidate on top of pile
+	// k, and back links each candidate to the top of the pile to its left.
+	var piles []int
+	back := make([]int, len(candidates))
+	for i, c := range candidates {
+		k := sort.Search(len(piles), func(k int) bool {
+			return candidates[piles[k]].b > c.b
+		})
+		back[i] = -1
+		if k > 0 {
+			back[i] = piles[k-1]
+		}
+		if k == len(piles) {
+			piles = append(piles, i)
+		} else {
+			piles[k] = i
+		}
+	}
+	if len(piles) == 0 {
+		return nil
+	}
+
+	anchors := make([]anchor, len(piles))
+	for i, k := piles[len(piles)-1], len(piles)-1; i >= 0; i, k = back[i], k-1 {
+		anchors[k] = candidates[i]
+	}
+	return anchors
+}
+
+// histogramMaxChain bounds how often an anchor line may occur in a before
+// Histogram gives up on it and falls back to Myers.
+const histogramMaxChain = 64
+
+func (s *search) histogram(a, b []string) []Diff {
+	return withCommonEnds(a, b, func(a, b []string) []Diff {
+		if len(a) == 0 || len(b) == 0 || s.cancelled() {
+			return s.shortestEdit(a, b)
+		}
+
+		positions := make(map[string][]int)
+		for i, line := range a {
+			positions[line] = append(positions[line], i)
+		}
+
+		var (
+			bestA, bestB, bestLen int
+			bestCount             = histogramMaxChain + 1
+		)
+		for j := 0; j < len(b); j++ {
+			pos := positions[b[j]]
+			if len(pos) == 0 || len(pos) > bestCount {
+				continue
+			}
+			for _, i := range pos {
+				s, t := i, j
+				for s > 0 && t > 0 && a[s-1] == b[t-1] {
+					s--
+					t--
+				}
+				e, f := i+1, j+1
+				for e < len(a) && f < len(b) && a[e] == b[f] {
+					e++
+					f++
+				}
+
+				count := len(pos)
+				for x := s; x < e; x++ {
+					count = min(count, len(positions[a[x]]))
+				}
+				if count < bestCount || (count == bestCount && e-s > bestLen) {
+					bestA, bestB, bestLen, bestCount = s, t, e-s, count
+				}
+			}
+		}
+		if bestLen == 0 {
+			return s.shortestEdit(a, b)
+		}
+
+		diffs := s.histogram(a[:bestA], b[:bestB])
+		diffs = append(diffs, sameLines(a[bestA:bestA+bestLen])...)
+		return append(diffs, s.histogram(a[bestA+bestLen:], b[bestB+bestLen:])...)
+	})
+}
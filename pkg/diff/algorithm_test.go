@@ -0,0 +1,117 @@
+package diff
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDifferApplies(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	alphabet := []string{"a", "b", "c", "d", "{", "}", ""}
+
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(20))
+		for i := range lines {
+			lines[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return lines
+	}
+
+	for _, name := range Algorithms {
+		differ, err := NewDiffer(name)
+		if err != nil {
+			t.Fatalf("NewDiffer(%q) error = %v", name, err)
+		}
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 1000; i++ {
+				lines1, lines2 := randomLines(), randomLines()
+				old, new := applyDiffs(differ.Diff(lines1, lines2))
+				if strings.Join(old, "\n") != strings.Join(lines1, "\n") ||
+					strings.Join(new, "\n") != strings.Join(lines2, "\n") {
+					t.Fatalf("Diff(%q, %q) does not reproduce its inputs: %q, %q", lines1, lines2, old, new)
+				}
+			}
+		})
+	}
+}
+
+func TestPatienceAnchorsOnUniqueLines(t *testing.T) {
+	// Swapping two functions: both anchored algorithms keep func b() as
+	// context instead of pairing up stray braces. The final brace belongs to
+	// the common suffix.
+	lines1 := []string{"func a() {", "  one()", "}", "func b() {", "  two()", "}"}
+	lines2 := []string{"func b() {", "  two()", "}", "func a() {", "  one()", "}"}
+	want := []Diff{
+		{Line: "func a() {", Type: "remove", OldLine: 1},
+		{Line: "  one()", Type: "remove", OldLine: 2},
+		{Line: "}", Type: "remove", OldLine: 3},
+		{Line: "func b() {", Type: "same", OldLine: 4, NewLine: 1},
+		{Line: "  two()", Type: "same", OldLine: 5, NewLine: 2},
+		{Line: "}", Type: "add", NewLine: 3},
+		{Line: "func a() {", Type: "add", NewLine: 4},
+		{Line: "  one()", Type: "add", NewLine: 5},
+		{Line: "}", Type: "same", OldLine: 6, NewLine: 6},
+	}
+
+	for _, differ := range []Differ{Patience{}, Histogram{}} {
+		if got := differ.Diff(lines1, lines2); !diffsEqual(got, want) {
+			t.Errorf("%T.Diff() = %v, want %v", differ, got, want)
+		}
+	}
+}
+
+func TestNewDifferUnknown(t *testing.T) {
+	if _, err := NewDiffer("bogus"); err == nil {
+		t.Error("NewDiffer(\"bogus\") error = nil, want error")
+	}
+}
+
+func TestMaxCostApplies(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	alphabet := []string{"a", "b", "c", "d", "{", "}", ""}
+
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(40))
+		for i := range lines {
+			lines[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return lines
+	}
+
+	for _, name := range Algorithms {
+		differ, _ := NewDiffer(name)
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 1000; i++ {
+				lines1, lines2 := randomLines(), randomLines()
+				opts := Options{Algorithm: differ, MaxCost: 1 + rng.Intn(5)}
+				diffs, err := opts.diff(context.Background(), lines1, lines2)
+				if err != nil {
+					t.Fatalf("diff() error = %v", err)
+				}
+				old, new := applyDiffs(diffs)
+				if strings.Join(old, "\n") != strings.Join(lines1, "\n") ||
+					strings.Join(new, "\n") != strings.Join(lines2, "\n") {
+					t.Fatalf("diff(%q, %q) with MaxCost %d does not reproduce its inputs: %q, %q", lines1, lines2, opts.MaxCost, old, new)
+				}
+			}
+		})
+	}
+}
+
+func TestMaxCostKeepsSmallEditsMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(6))
+	for i := 0; i < 500; i++ {
+		a := randomText(rng)
+		b := mutate(rng, a)
+		want := LineByLine(a, b)
+		got, err := Options{MaxCost: 8}.diff(context.Background(), a, b)
+		if err != nil {
+			t.Fatalf("diff() error = %v", err)
+		}
+		if !diffsEqual(got, want) {
+			t.Fatalf("diff(%q, %q) with MaxCost = %v, want %v", a, b, got, want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package diff
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Invert returns the edit script that undoes diffs: additions become
+// removals and the other way round, moves run the other way, changes swap
+// their old and new text, and old and new line numbers swap.
+func Invert(diffs []Diff) []Diff {
+	inverted := make([]Diff, len(diffs))
+	for i, d := range diffs {
+		switch d.Type {
+		case "add":
+			d.Type = "remove"
+		case "remove":
+			d.Type = "add"
+		case "move_to":
+			d.Type = "move_from"
+		case "move_from":
+			d.Type = "move_to"
+		case "change":
+			d.Line, d.OldText = d.OldText, d.Line
+		}
+		d.OldLine, d.NewLine = d.NewLine, d.OldLine
+		inverted[i] = d
+	}
+	return removalsFirst(inverted)
+}
+
+// InvertPatch returns the patch that undoes p.
+func InvertPatch(p FilePatch) FilePatch {
+	inverted := FilePatch{OldName: p.NewName, NewName: p.OldName}
+	for _, h := range p.Hunks {
+		inverted.Hunks = append(inverted.Hunks, Hunk{
+			OldStart: h.NewStart,
+			OldLines: h.NewLines,
+			NewStart: h.OldStart,
+			NewLines: h.OldLines,
+			Lines:    Invert(h.Lines),
+		})
+	}
+	return inverted
+}
+
+// removalsFirst reorders every run of removed and added lines so its
+// removals come before its additions, the order the diff algorithms
+// produce. Changed lines, like unchanged ones, end a run.
+func removalsFirst(diffs []Diff) []Diff {
+	removal := func(d Diff) bool { return d.Type == "remove" || d.Type == "move_from" }
+	for i := 0; i < len(diffs); {
+		if diffs[i].Type == "same" || diffs[i].Type == "change" {
+			i++
+			continue
+		}
+		end := i
+		for end < len(diffs) && diffs[end].Type != "same" && diffs[end].Type != "change" {
+			end++
+		}
+		sort.SliceStable(diffs[i:end], func(a, b int) bool {
+			return removal(diffs[i+a]) && !removal(diffs[i+b])
+		})
+		i = end
+	}
+	return diffs
+}
+
+// plainDiffs returns diffs with every change split into a removal and an
+// addition and moved lines as plain removals and additions.
+func plainDiffs(diffs []Diff) []Diff {
+	plain := make([]Diff, 0, len(diffs))
+	for _, d := range diffs {
+		switch d.Type {
+		case "change":
+			plain = append(plain,
+				Diff{Line: d.OldText, Type: "remove", OldLine: d.OldLine, NoNewline: d.NoNewline},
+				Diff{Line: d.Line, Type: "add", NewLine: d.NewLine, NoNewline: d.NoNewline})
+		case "move_from":
+			plain = append(plain, Diff{Line: d.Line, Type: "remove", OldLine: d.OldLine, NoNewline: d.NoNewline})
+		case "move_to":
+			plain = append(plain, Diff{Line: d.Line, Type: "add", NewLine: d.NewLine, NoNewline: d.NoNewline})
+		default:
+			plain = append(plain, d)
+		}
+	}
+	return plain
+}
+
+// Compose combines an edit script from A to B with one from B to C into a
+// single script from A to C. Both scripts must agree on the lines of B.
+// Changed and moved lines come out as plain removals and additions.
+func Compose(ab, bc []Diff) ([]Diff, error) {
+	ab, bc = plainDiffs(ab), plainDiffs(bc)
+	var ac []Diff
+	i, j := 0, 0
+	for i < len(ab) || j < len(bc) {
+		switch {
+		case i < len(ab) && ab[i].Type == "remove":
+			ac = append(ac, Diff{Line: ab[i].Line, Type: "remove", NoNewline: ab[i].NoNewline})
+			i++
+		case j < len(bc) && bc[j].Type == "add":
+			ac = append(ac, Diff{Line: bc[j].Line, Type: "add", NoNewline: bc[j].NoNewline})
+			j++
+		case i < len(ab) && j < len(bc):
+			// Both scripts are at the same line of B.
+			if ab[i].Line != bc[j].Line || ab[i].NoNewline != bc[j].NoNewline {
+				return nil, fmt.Errorf("scripts disagree on a line of the intermediate text: %q vs %q", ab[i].Line, bc[j].Line)
+			}
+			switch {
+			case ab[i].Type == "same" && bc[j].Type == "same":
+				ac = append(ac, Diff{Line: ab[i].Line, Type: "same", NoNewline: ab[i].NoNewline})
+			case ab[i].Type == "same":
+				ac = append(ac, Diff{Line: ab[i].Line, Type: "remove", NoNewline: ab[i].NoNewline})
+			case bc[j].Type == "same":
+				ac = append(ac, Diff{Line: bc[j].Line, Type: "add", NoNewline: bc[j].NoNewline})
+			}
+			// A line added by ab and removed by bc vanishes.
+			i++
+			j++
+		default:
+			return nil, fmt.Errorf("scripts disagree on the length of the intermediate text")
+		}
+	}
+	return numberLines(removalsFirst(ac)), nil
+}
+
+// ComposePatches combines a patch from A to B with a patch from B to C
+// into one from A to C. Unlike Compose it only needs the hunks: a line of
+// B shown by one patch but outside the hunks of the other is unchanged by
+// that other patch, so between them the hunks carry all the text needed.
+func ComposePatches(ab, bc FilePatch) (FilePatch, error) {
+	type span struct {
+		lo, hi  int // lines [lo, hi) of B, 1-based
+		abHunks []Hunk
+		bcHunks []Hunk
+	}
+	var spans []span
+	for _, h := range ab.Hunks {
+		lo := h.newFirst()
+		spans = append(spans, span{lo: lo, hi: lo + h.NewLines, abHunks: []Hunk{h}})
+	}
+	for _, h := range bc.Hunks {
+		lo := h.oldFirst()
+		spans = append(spans, span{lo: lo, hi: lo + h.OldLines, bcHunks: []Hunk{h}})
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].lo < spans[j].lo })
+
+	var merged []span
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.lo <= merged[n-1].hi {
+			last := &merged[n-1]
+			last.hi = max(last.hi, s.hi)
+			last.abHunks = append(last.abHunks, s.abHunks...)
+			last.bcHunks = append(last.bcHunks, s.bcHunks...)
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	// Every line of B that either patch shows.
+	lineOfB := make(map[int]Diff)
+	for _, h := range ab.Hunks {
+		for _, d := range h.Lines {
+			if d.Type != "remove" {
+				lineOfB[d.NewLine] = d
+			}
+		}
+	}
+	for _, h := range bc.Hunks {
+		for _, d := range h.Lines {
+			if d.Type != "add" {
+				lineOfB[d.OldLine] = d
+			}
+		}
+	}
+
+	// script expands the hunks of one patch over lines [lo, hi) of B,
+	// filling the gaps between hunks with unchanged lines.
+	script := func(hunks []Hunk, lo, hi int, bStart func(Hunk) int, bLine func(Diff) int) ([]Diff, error) {
+		var diffs []Diff
+		at := lo
+		fill := func(to int) error {
+			for ; at < to; at++ {
+				d, ok := lineOfB[at]
+				if !ok {
+					return fmt.Errorf("line %d of the intermediate file is not covered by either patch", at)
+				}
+				diffs = append(diffs, Diff{Line: d.Line, Type: "same", NoNewline: d.NoNewline})
+			}
+			return nil
+		}
+		for _, h := range hunks {
+			if err := fill(bStart(h)); err != nil {
+				return nil, err
+			}
+			for _, d := range h.Lines {
+				if n := bLine(d); n > 0 {
+					if err := fill(n); err != nil {
+						return nil, err
+					}
+					at = n + 1
+				}
+				diffs = append(diffs, d)
+			}
+		}
+		if err := fill(hi); err != nil {
+			return nil, err
+		}
+		return diffs, nil
+	}
+
+	composed := FilePatch{OldName: ab.OldName, NewName: bc.NewName}
+	deltaAB, deltaBC := 0, 0 // how far A and C are shifted from B before the span
+	for _, s := range merged {
+		abScript, err := script(s.abHunks, s.lo, s.hi, Hunk.newFirst, func(d Diff) int {
+			if d.Type == "remove" {
+				return 0
+			}
+			return d.NewLine
+		})
+		if err != nil {
+			return FilePatch{}, err
+		}
+		bcScript, err := script(s.bcHunks, s.lo, s.hi, Hunk.oldFirst, func(d Diff) int {
+			if d.Type == "add" {
+				return 0
+			}
+			return d.OldLine
+		})
+		if err != nil {
+			return FilePatch{}, err
+		}
+		lines, err := Compose(abScript, bcScript)
+		if err != nil {
+			return FilePatch{}, err
+		}
+
+		oldFirst, newFirst := s.lo+deltaAB, s.lo+deltaBC
+		for _, h := range s.abHunks {
+			deltaAB += h.OldLines - h.NewLines
+		}
+		for _, h := range s.bcHunks {
+			deltaBC += h.NewLines - h.OldLines
+		}
+
+		if !HasChanges(lines) {
+			continue
+		}
+		composed.Hunks = append(composed.Hunks, hunkAt(lines, oldFirst, newFirst))
+	}
+	return composed, nil
+}
+
+// hunkAt numbers lines starting at the given first old and new line and
+// wraps them in a hunk.
+func hunkAt(lines []Diff, oldFirst, newFirst int) Hunk {
+	h := Hunk{Lines: lines}
+	oldLine, newLine := oldFirst, newFirst
+	for i := range lines {
+		lines[i].OldLine, lines[i].NewLine = 0, 0
+		if lines[i].Type != "add" {
+			lines[i].OldLine = oldLine
+			oldLine++
+			h.OldLines++
+		}
+		if lines[i].Type != "remove" {
+			lines[i].NewLine = newLine
+			newLine++
+			h.NewLines++
+		}
+	}
+	h.OldStart, h.NewStart = oldFirst, newFirst
+	if h.OldLines == 0 {
+		h.OldStart--
+	}
+	if h.NewLines == 0 {
+		h.NewStart--
+	}
+	return h
+}
+
+// ComposePatchSets composes every file patch of ab with the patch of bc
+// that continues it. A patch of bc continues one of ab when its old name
+// matches ab's new name, either verbatim or once the leading a/ or b/ style
+// directory is stripped from both. Files touched by only one side are
+// passed through unchanged.
+func ComposePatchSets(ab, bc []FilePatch) ([]FilePatch, error) {
+	var composed []FilePatch
+	used := make([]bool, len(bc))
+	for _, p := range ab {
+		next := -1
+		for j := range bc {
+			if !used[j] && samePatchFile(p.NewName, bc[j].OldName) {
+				next = j
+				break
+			}
+		}
+		if next < 0 {
+			composed = append(composed, p)
+			continue
+		}
+		used[next] = true
+		c, err := ComposePatches(p, bc[next])
+		if err != nil {
+			return nil, fmt.Errorf("composing %s: %w", p.NewName, err)
+		}
+		composed = append(composed, c)
+	}
+	for j, p := range bc {
+		if !used[j] {
+			composed = append(composed, p)
+		}
+	}
+	return composed, nil
+}
+
+func samePatchFile(name1, name2 string) bool {
+	if name1 == name2 {
+		return true
+	}
+	strip := func(name string) string {
+		name = filepath.ToSlash(name)
+		if i := strings.IndexByte(name, '/'); i >= 0 {
+			return name[i+1:]
+		}
+		return name
+	}
+	return strip(name1) == strip(name2)
+}
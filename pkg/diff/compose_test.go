@@ -0,0 +1,188 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// mutate returns a copy of lines with a few random edits.
+func mutate(rng *rand.Rand, lines []string) []string {
+	out := append([]string(nil), lines...)
+	for n := rng.Intn(4); n > 0; n-- {
+		switch i := rng.Intn(len(out) + 1); rng.Intn(3) {
+		case 0:
+			out = append(out[:i], append([]string{string(rune('a' + rng.Intn(26)))}, out[i:]...)...)
+		case 1:
+			if i < len(out) {
+				out = append(out[:i], out[i+1:]...)
+			}
+		default:
+			if i < len(out) {
+				out[i] = strings.ToUpper(out[i]) + "!"
+			}
+		}
+	}
+	return out
+}
+
+func randomText(rng *rand.Rand) []string {
+	lines := make([]string, 5+rng.Intn(30))
+	for i := range lines {
+		lines[i] = string(rune('a' + rng.Intn(26)))
+	}
+	return lines
+}
+
+func TestInvert(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 500; i++ {
+		a := randomText(rng)
+		b := mutate(rng, a)
+		ab := LineByLine(a, b)
+
+		ba := Invert(ab)
+		if want := LineByLine(b, a); len(ba) != len(want) {
+			t.Fatalf("Invert() has %d entries, want %d", len(ba), len(want))
+		}
+		old, new := applyDiffs(ba)
+		if strings.Join(old, "\n") != strings.Join(b, "\n") || strings.Join(new, "\n") != strings.Join(a, "\n") {
+			t.Fatalf("Invert(%v) = %v does not turn B back into A", ab, ba)
+		}
+		for _, d := range ba {
+			if d.Type != "add" && b[d.OldLine-1] != d.Line || d.Type != "remove" && a[d.NewLine-1] != d.Line {
+				t.Fatalf("Invert() entry %+v has wrong line numbers", d)
+			}
+		}
+		if again := Invert(ba); !diffsEqual(again, ab) {
+			t.Fatalf("Invert(Invert(x)) = %v, want %v", again, ab)
+		}
+	}
+}
+
+func TestCompose(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	for i := 0; i < 500; i++ {
+		a := randomText(rng)
+		b := mutate(rng, a)
+		c := mutate(rng, b)
+
+		ac, err := Compose(LineByLine(a, b), LineByLine(b, c))
+		if err != nil {
+			t.Fatalf("Compose() error = %v", err)
+		}
+		old, new := applyDiffs(ac)
+		if strings.Join(old, "\n") != strings.Join(a, "\n") || strings.Join(new, "\n") != strings.Join(c, "\n") {
+			t.Fatalf("Compose() = %v does not turn %v into %v", ac, a, c)
+		}
+	}
+}
+
+func TestComposeMismatch(t *testing.T) {
+	ab := LineByLine([]string{"a"}, []string{"b"})
+	bc := LineByLine([]string{"x"}, []string{"c"})
+	if _, err := Compose(ab, bc); err == nil {
+		t.Error("Compose() error = nil, want error for scripts with different intermediate texts")
+	}
+}
+
+func TestComposePatches(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	join := func(lines []string) string {
+		if len(lines) == 0 {
+			return ""
+		}
+		return strings.Join(lines, "\n") + "\n"
+	}
+
+	for i := 0; i < 500; i++ {
+		a := randomText(rng)
+		b := mutate(rng, a)
+		c := mutate(rng, b)
+		context := rng.Intn(3)
+
+		ab := FilePatch{OldName: "a/f", NewName: "b/f", Hunks: Hunks(LineByLine(a, b), context)}
+		bc := FilePatch{OldName: "b/f", NewName: "c/f", Hunks: Hunks(LineByLine(b, c), context)}
+		ac, err := ComposePatches(ab, bc)
+		if err != nil {
+			t.Fatalf("ComposePatches() error = %v", err)
+		}
+
+		result, err := Apply(strings.NewReader(join(a)), ac.Hunks, 0)
+		if err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if len(result.Rejected) > 0 {
+			t.Fatalf("composed patch %v rejected on %v", ac.Hunks, a)
+		}
+		if got := string(result.Content); got != join(c) {
+			t.Fatalf("composed patch turns %q into %q, want %q", join(a), got, join(c))
+		}
+	}
+}
+
+// preparedDiffs diffs a and b the way the renderers show them, with
+// similar lines paired into changes and moved blocks detected.
+func preparedDiffs(rng *rand.Rand) (a, b []string, diffs []Diff) {
+	for _, line := range randomText(rng) {
+		a = append(a, "12 34 "+line)
+	}
+	b = mutate(rng, a)
+	if len(b) > 4 && rng.Intn(2) == 0 {
+		// Move the first two lines to the end.
+		b = append(append([]string(nil), b[2:]...), b[:2]...)
+	}
+	return a, b, DetectMoves(PairChanges(LineByLine(a, b)), 2)
+}
+
+// sides reconstructs both inputs of an edit script of any type.
+func sides(diffs []Diff) (old, new []string) {
+	for _, d := range plainDiffs(diffs) {
+		if d.inOld() {
+			old = append(old, d.Line)
+		}
+		if d.inNew() {
+			new = append(new, d.Line)
+		}
+	}
+	return old, new
+}
+
+func TestInvertPrepared(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	for i := 0; i < 500; i++ {
+		a, b, ab := preparedDiffs(rng)
+		ba := Invert(ab)
+		old, new := sides(ba)
+		if strings.Join(old, "\n") != strings.Join(b, "\n") || strings.Join(new, "\n") != strings.Join(a, "\n") {
+			t.Fatalf("Invert(%v) = %v does not turn B back into A", ab, ba)
+		}
+		for _, d := range ba {
+			oldText := d.Line
+			if d.Type == "change" {
+				oldText = d.OldText
+			}
+			if d.inOld() && b[d.OldLine-1] != oldText || d.inNew() && a[d.NewLine-1] != d.Line {
+				t.Fatalf("Invert() entry %+v has wrong line numbers", d)
+			}
+		}
+		if again := Invert(ba); !diffsEqual(again, ab) {
+			t.Fatalf("Invert(Invert(x)) = %v, want %v", again, ab)
+		}
+	}
+}
+
+func TestComposePrepared(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	for i := 0; i < 500; i++ {
+		a, b, ab := preparedDiffs(rng)
+		ac, err := Compose(ab, Invert(ab))
+		if err != nil {
+			t.Fatalf("Compose() error = %v", err)
+		}
+		old, new := applyDiffs(ac)
+		if strings.Join(old, "\n") != strings.Join(a, "\n") || strings.Join(new, "\n") != strings.Join(a, "\n") {
+			t.Fatalf("Compose() of %v and its inverse = %v, want no change to %v", ab, ac, b)
+		}
+	}
+}
@@ -3,29 +3,136 @@ package diff
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
+// Diff is one line of an edit script. OldLine and NewLine are 1-based line
+// numbers in the first and second input; each is zero on the side where
+// the line does not exist. NoNewline marks the last line of an input that
+// does not end in a newline. OldText is only set on "change" entries, see
+// PairChanges. MoveID links the "move_from" and "move_to" lines of a moved
+// block, see DetectMoves.
 type Diff struct {
-	Line string
-	Type string
+	Line      string
+	Type      string
+	OldLine   int
+	NewLine   int
+	NoNewline bool
+	OldText   string
+	MoveID    int
 }
 
-func Files(file1, file2 io.Reader) ([]Diff, error) {
-	lines1, err := readLines(file1)
+// inOld reports whether d has a line in the first input.
+func (d Diff) inOld() bool {
+	return d.Type != "add" && d.Type != "move_to"
+}
+
+// inNew reports whether d has a line in the second input.
+func (d Diff) inNew() bool {
+	return d.Type != "remove" && d.Type != "move_from"
+}
+
+func Files(file1, file2 io.Reader, opts Options) ([]Diff, error) {
+	return FilesContext(context.Background(), file1, file2, opts)
+}
+
+// FilesContext is Files that gives up with ctx.Err() once ctx is done.
+func FilesContext(ctx context.Context, file1, file2 io.Reader, opts Options) ([]Diff, error) {
+	text1, err := readText(file1)
 	if err != nil {
 		return nil, err
 	}
-	lines2, err := readLines(file2)
+	text2, err := readText(file2)
 	if err != nil {
 		return nil, err
 	}
 
-	return LineByLine(lines1, lines2), nil
+	return opts.compare(ctx, text1, text2)
+}
+
+func FilesDiff(file1, file2 io.Reader, opts Options) ([]Diff, bool, error) {
+	return filesDiff(context.Background(), file1, file2, opts)
+}
+
+// FilesDiffContext is FilesDiff that gives up with ctx.Err() once ctx is
+// done.
+func FilesDiffContext(ctx context.Context, file1, file2 io.Reader, opts Options) ([]Diff, bool, error) {
+	return filesDiff(ctx, file1, file2, opts)
 }
 
-func FilesDiff(file1, file2 io.Reader) ([]Diff, bool, error) {
+// errDiffer stops a comparison at the first difference.
+var errDiffer = errors.New("inputs differ")
+
+// FilesDiffer reports whether Files would find changes, reading file1 and
+// file2 only as far as the first one.
+func FilesDiffer(file1, file2 io.Reader, opts Options) (bool, error) {
+	return filesDiffer(context.Background(), file1, file2, opts)
+}
+
+func filesDiffer(ctx context.Context, file1, file2 io.Reader, opts Options) (bool, error) {
+	// As in filesDiff, binary files are compared byte for byte, whatever
+	// the options say to ignore. Peeking leaves pipes intact.
+	r1, r2 := bufio.NewReader(file1), bufio.NewReader(file2)
+	isBin1, err := peekBinary(r1)
+	if err != nil {
+		return false, fmt.Errorf("checking file1: %w", err)
+	}
+	isBin2, err := peekBinary(r2)
+	if err != nil {
+		return false, fmt.Errorf("checking file2: %w", err)
+	}
+	if isBin1 || isBin2 {
+		return bytesDiffer(r1, r2)
+	}
+
+	err = StreamContext(ctx, r1, r2, opts, func(d Diff) error {
+		if d.Type != "same" {
+			return errDiffer
+		}
+		return nil
+	})
+	if errors.Is(err, errDiffer) {
+		return true, nil
+	}
+	return false, err
+}
+
+// peekBinary is IsBinary without consuming what it looks at.
+func peekBinary(r *bufio.Reader) (bool, error) {
+	head, err := r.Peek(512)
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("reading file for binary check: %w", err)
+	}
+	return IsBinary(bytes.NewReader(head))
+}
+
+// bytesDiffer reports whether r1 and r2 hold different bytes, reading them
+// only as far as the first difference.
+func bytesDiffer(r1, r2 io.Reader) (bool, error) {
+	buf1, buf2 := make([]byte, 32*1024), make([]byte, 32*1024)
+	for {
+		n1, err1 := io.ReadFull(r1, buf1)
+		if err1 != nil && err1 != io.EOF && err1 != io.ErrUnexpectedEOF {
+			return false, fmt.Errorf("reading file1: %w", err1)
+		}
+		n2, err2 := io.ReadFull(r2, buf2)
+		if err2 != nil && err2 != io.EOF && err2 != io.ErrUnexpectedEOF {
+			return false, fmt.Errorf("reading file2: %w", err2)
+		}
+		if !bytes.Equal(buf1[:n1], buf2[:n2]) {
+			return true, nil
+		}
+		if n1 < len(buf1) {
+			return false, nil
+		}
+	}
+}
+
+func filesDiff(ctx context.Context, file1, file2 io.Reader, opts Options) ([]Diff, bool, error) {
 	// Check if files are likely binary.  If so, don't do line-by-line.
 	isBin1, err1 := IsBinary(file1)
 	isBin2, err2 := IsBinary(file2)
@@ -65,42 +172,81 @@ func FilesDiff(file1, file2 io.Reader) ([]Diff, bool, error) {
 		return nil, false, nil //Binary and equal
 	}
 
-	lines1, err := readLines(file1)
+	text1, err := readText(file1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	text2, err := readText(file2)
 	if err != nil {
 		return nil, false, err
 	}
 
-	lines2, err := readLines(file2)
+	diffs, err := opts.compare(ctx, text1, text2)
 	if err != nil {
 		return nil, false, err
 	}
+	return diffs, false, nil
+}
 
-	return LineByLine(lines1, lines2), false, nil
+// text is an input split into lines without their trailing newlines.
+type text struct {
+	lines          []string
+	missingNewline bool
 }
 
-func readLines(r io.Reader) ([]string, error) {
-	var lines []string
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+// keys returns the lines the diff algorithm compares, normalized as opts
+// asks. The last line of a text without a trailing newline is tagged so it
+// only matches its twin.
+func (t text) keys(opts Options) []string {
+	keys := make([]string, len(t.lines))
+	for i, line := range t.lines {
+		keys[i] = opts.normalize(line)
+	}
+	if t.missingNewline {
+		keys[len(keys)-1] += "\x00"
+	}
+	return keys
+}
+
+func readText(r io.Reader) (text, error) {
+	var t text
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			if strings.HasSuffix(line, "\n") {
+				line = line[:len(line)-1]
+			} else {
+				t.missingNewline = true
+			}
+			t.lines = append(t.lines, line)
+		}
+		if err == io.EOF {
+			return t, nil
+		}
+		if err != nil {
+			return text{}, err
+		}
 	}
-	return lines, scanner.Err()
 }
 
 func LineByLine(lines1, lines2 []string) []Diff {
-	var diffs []Diff
-	i, j := 0, 0
-	for i < len(lines1) || j < len(lines2) {
-		if i < len(lines1) && j < len(lines2) && lines1[i] == lines2[j] {
-			diffs = append(diffs, Diff{Line: lines1[i], Type: "same"})
-			i++
-			j++
-		} else if i < len(lines1) && (j >= len(lines2) || lines1[i] < lines2[j]) { // KEY CHANGE
-			diffs = append(diffs, Diff{Line: lines1[i], Type: "remove"})
-			i++
-		} else { // Simplified the else condition
-			diffs = append(diffs, Diff{Line: lines2[j], Type: "add"})
-			j++
+	return numberLines(myers(lines1, lines2))
+}
+
+// numberLines fills in OldLine and NewLine by walking the edit script.
+func numberLines(diffs []Diff) []Diff {
+	oldLine, newLine := 0, 0
+	for i := range diffs {
+		diffs[i].OldLine, diffs[i].NewLine = 0, 0
+		if diffs[i].Type != "add" {
+			oldLine++
+			diffs[i].OldLine = oldLine
+		}
+		if diffs[i].Type != "remove" {
+			newLine++
+			diffs[i].NewLine = newLine
 		}
 	}
 	return diffs
@@ -1,8 +1,15 @@
 package diff
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestFiles(t *testing.T) {
@@ -15,42 +22,42 @@ func TestFiles(t *testing.T) {
 	}{
 		{
 			name:   "identical files",
-			input1: "line1\nline2",
-			input2: "line1\nline2",
+			input1: "line1\nline2\n",
+			input2: "line1\nline2\n",
 			want: []Diff{
-				{Line: "line1", Type: "same"},
-				{Line: "line2", Type: "same"},
+				{Line: "line1", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "line2", Type: "same", OldLine: 2, NewLine: 2},
 			},
 			wantErr: false,
 		},
 		{
 			name:   "one line added",
-			input1: "line1",
-			input2: "line1\nline2",
+			input1: "line1\n",
+			input2: "line1\nline2\n",
 			want: []Diff{
-				{Line: "line1", Type: "same"},
-				{Line: "line2", Type: "add"},
+				{Line: "line1", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "line2", Type: "add", NewLine: 2},
 			},
 			wantErr: false,
 		},
 		{
 			name:   "one line removed",
-			input1: "line1\nline2",
-			input2: "line1",
+			input1: "line1\nline2\n",
+			input2: "line1\n",
 			want: []Diff{
-				{Line: "line1", Type: "same"},
-				{Line: "line2", Type: "remove"},
+				{Line: "line1", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "line2", Type: "remove", OldLine: 2},
 			},
 			wantErr: false,
 		},
 		{
 			name:   "one line changed",
-			input1: "line1\nline2",
-			input2: "line1\nline3",
+			input1: "line1\nline2\n",
+			input2: "line1\nline3\n",
 			want: []Diff{
-				{Line: "line1", Type: "same"},
-				{Line: "line2", Type: "remove"},
-				{Line: "line3", Type: "add"},
+				{Line: "line1", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "line2", Type: "remove", OldLine: 2},
+				{Line: "line3", Type: "add", NewLine: 2},
 			},
 			wantErr: false,
 		},
@@ -62,12 +69,33 @@ func TestFiles(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name:   "added to the beginning", //Added new test case!
-			input1: "line2",
+			name:   "missing newline on both sides",
+			input1: "line1\nline2",
 			input2: "line1\nline2",
 			want: []Diff{
-				{Line: "line1", Type: "add"},
-				{Line: "line2", Type: "same"},
+				{Line: "line1", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "line2", Type: "same", OldLine: 2, NewLine: 2, NoNewline: true},
+			},
+			wantErr: false,
+		},
+		{
+			name:   "newline added at end of file",
+			input1: "line1\nline2",
+			input2: "line1\nline2\n",
+			want: []Diff{
+				{Line: "line1", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "line2", Type: "remove", OldLine: 2, NoNewline: true},
+				{Line: "line2", Type: "add", NewLine: 2},
+			},
+			wantErr: false,
+		},
+		{
+			name:   "added to the beginning", //Added new test case!
+			input1: "line2\n",
+			input2: "line1\nline2\n",
+			want: []Diff{
+				{Line: "line1", Type: "add", NewLine: 1},
+				{Line: "line2", Type: "same", OldLine: 1, NewLine: 2},
 			},
 			wantErr: false,
 		},
@@ -78,7 +106,7 @@ func TestFiles(t *testing.T) {
 			reader1 := strings.NewReader(tt.input1)
 			reader2 := strings.NewReader(tt.input2)
 
-			got, err := Files(reader1, reader2)
+			got, err := Files(reader1, reader2, Options{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Files() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -102,3 +130,196 @@ func diffsEqual(a, b []Diff) bool {
 	}
 	return true
 }
+
+// unrelatedLines returns n lines sharing nothing with those of another
+// prefix, the worst case for a minimal diff.
+func unrelatedLines(prefix string, n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteString(prefix + strconv.Itoa(i) + "\n")
+	}
+	return b.String()
+}
+
+func TestFilesContext(t *testing.T) {
+	input1, input2 := unrelatedLines("a", 20000), unrelatedLines("b", 20000)
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := FilesContext(ctx, strings.NewReader(input1), strings.NewReader(input2), Options{})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("FilesContext() error = %v, want %v", err, context.Canceled)
+		}
+	})
+
+	for _, name := range Algorithms {
+		differ, _ := NewDiffer(name)
+		t.Run(name+" deadline", func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			_, err := FilesContext(ctx, strings.NewReader(input1), strings.NewReader(input2), Options{Algorithm: differ})
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("FilesContext() error = %v, want %v", err, context.DeadlineExceeded)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("FilesContext() took %v to notice the deadline", elapsed)
+			}
+		})
+	}
+
+	t.Run("heuristic", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		diffs, err := FilesContext(ctx, strings.NewReader(input1), strings.NewReader(input2), Options{MaxCost: HeuristicMaxCost})
+		if err != nil {
+			t.Fatalf("FilesContext() with MaxCost error = %v", err)
+		}
+		if len(diffs) != 40000 {
+			t.Errorf("FilesContext() with MaxCost returned %d diffs, want 40000", len(diffs))
+		}
+	})
+}
+
+func TestDirectoryDiffsContextCancelled(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	for _, dir := range []string{dir1, dir2} {
+		if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte(dir+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DirectoryDiffsContext(ctx, dir1, dir2, Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("DirectoryDiffsContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestStructuralDiffsContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	src := "package p\n\nfunc f() {}\n"
+	if _, err := StructuralDiffsContext(ctx, strings.NewReader(src), strings.NewReader(src)); !errors.Is(err, context.Canceled) {
+		t.Errorf("StructuralDiffsContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFilesDiffer(t *testing.T) {
+	long := strings.Repeat("same\n", 3*streamWindow)
+	longBinary := "\x00" + strings.Repeat("x", 64*1024)
+	tests := []struct {
+		name   string
+		input1 string
+		input2 string
+		opts   Options
+		want   bool
+	}{
+		{name: "identical", input1: "a\nb\n", input2: "a\nb\n", want: false},
+		{name: "changed", input1: "a\nb\n", input2: "a\nc\n", want: true},
+		{name: "missing newline", input1: "a\n", input2: "a", want: true},
+		{name: "change after many windows", input1: long + "a\n", input2: long + "b\n", want: true},
+		{name: "blank lines ignored", input1: "a\nb\n", input2: "a\n\nb\n", opts: Options{IgnoreBlankLines: true}, want: false},
+		{name: "white space ignored", input1: "a b\n", input2: "a  b\n", opts: Options{IgnoreSpaceChange: true}, want: false},
+		{name: "binary", input1: "a\x00b\n", input2: "a\x00b\n", want: false},
+		{name: "binary with white space ignored", input1: "a\x00 b\n", input2: "a\x00b\n", opts: Options{IgnoreAllSpace: true}, want: true},
+		{name: "binary with blank lines ignored", input1: "a\x00\n", input2: "a\x00\n\n", opts: Options{IgnoreBlankLines: true}, want: true},
+		{name: "binary beyond one read", input1: longBinary + "a", input2: longBinary + "b", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FilesDiffer(strings.NewReader(tt.input1), strings.NewReader(tt.input2), tt.opts)
+			if err != nil {
+				t.Fatalf("FilesDiffer() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("FilesDiffer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBriefDirectoryDiffs(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(dir1, "same.txt"):    "a\n",
+		filepath.Join(dir2, "same.txt"):    "a\n",
+		filepath.Join(dir1, "changed.txt"): "a\n",
+		filepath.Join(dir2, "changed.txt"): "b\n",
+		filepath.Join(dir1, "removed.txt"): "a\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diffs, err := BriefDirectoryDiffs(context.Background(), dir1, dir2, Options{})
+	if err != nil {
+		t.Fatalf("BriefDirectoryDiffs() error = %v", err)
+	}
+	var got []string
+	for _, d := range diffs {
+		got = append(got, d.Type+" "+d.File1)
+		if d.Diffs != nil {
+			t.Errorf("BriefDirectoryDiffs() entry %s has Diffs", d.File1)
+		}
+	}
+	sort.Strings(got)
+	want := []string{"change changed.txt", "remove removed.txt", "same same.txt"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("BriefDirectoryDiffs() = %q, want %q", got, want)
+	}
+
+	for _, tt := range []struct {
+		dir2 string
+		want bool
+	}{{dir1, false}, {dir2, true}} {
+		differ, err := DirectoriesDiffer(context.Background(), dir1, tt.dir2, Options{})
+		if err != nil {
+			t.Fatalf("DirectoriesDiffer() error = %v", err)
+		}
+		if differ != tt.want {
+			t.Errorf("DirectoriesDiffer(%s, %s) = %v, want %v", dir1, tt.dir2, differ, tt.want)
+		}
+	}
+}
+
+func TestWalkDirectoryDiffs(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(dir1, "changed.txt"): "a\n",
+		filepath.Join(dir2, "changed.txt"): "b\n",
+		filepath.Join(dir2, "added.txt"):   "a\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	err := WalkDirectoryDiffs(context.Background(), dir1, dir2, Options{}, func(d DirectoryDiff) error {
+		got = append(got, d.Type+" "+d.File1+d.File2)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDirectoryDiffs() error = %v", err)
+	}
+	sort.Strings(got)
+	want := []string{"add added.txt", "change changed.txtchanged.txt"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("WalkDirectoryDiffs() = %q, want %q", got, want)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = WalkDirectoryDiffs(context.Background(), dir1, dir2, Options{}, func(DirectoryDiff) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("WalkDirectoryDiffs() = %v after %d calls, want %v after 1", err, calls, stop)
+	}
+}
@@ -1,10 +1,14 @@
 package diff
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
+	"time"
 )
 
 type DirectoryDiff struct {
@@ -13,17 +17,109 @@ type DirectoryDiff struct {
 	Type       string
 	Diffs      []Diff
 	BinaryDiff bool
+	ModTime1   time.Time
+	ModTime2   time.Time
 }
 
-func DirectoryDiffs(dir1, dir2 string) ([]DirectoryDiff, error) {
+func DirectoryDiffs(dir1, dir2 string, opts Options) ([]DirectoryDiff, error) {
+	return DirectoryDiffsContext(context.Background(), dir1, dir2, opts)
+}
+
+// DirectoryDiffsContext is DirectoryDiffs that gives up with ctx.Err()
+// once ctx is done, including in the middle of diffing a file.
+func DirectoryDiffsContext(ctx context.Context, dir1, dir2 string, opts Options) ([]DirectoryDiff, error) {
+	var diffs []DirectoryDiff
+	err := WalkDirectoryDiffs(ctx, dir1, dir2, opts, func(d DirectoryDiff) error {
+		diffs = append(diffs, d)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortDirectoryDiffs(diffs)
+	return diffs, nil
+}
+
+// WalkDirectoryDiffs is DirectoryDiffsContext for directories too large to
+// hold every diff in memory at once. Each entry is passed to fn as soon as
+// it is known, in the order the directories are walked rather than sorted.
+// An error from fn stops the walk and is returned.
+func WalkDirectoryDiffs(ctx context.Context, dir1, dir2 string, opts Options, fn func(DirectoryDiff) error) error {
+	compare := func(file1, file2 io.Reader, d *DirectoryDiff) error {
+		fileDiffs, binDiff, err := filesDiff(ctx, file1, file2, opts)
+		if err != nil {
+			return err
+		}
+		if binDiff {
+			d.BinaryDiff = true
+		} else if HasChanges(fileDiffs) {
+			d.Diffs = fileDiffs
+		} else {
+			d.Type = "same"
+		}
+		return nil
+	}
+	return walkDirectories(ctx, dir1, dir2, compare, fn)
+}
+
+// BriefDirectoryDiffs is DirectoryDiffsContext for when only which files
+// differ matters, as with diff -q. Files are read only as far as their
+// first difference, so changed files carry no Diffs.
+func BriefDirectoryDiffs(ctx context.Context, dir1, dir2 string, opts Options) ([]DirectoryDiff, error) {
 	var diffs []DirectoryDiff
+	err := walkDirectories(ctx, dir1, dir2, briefCompare(ctx, opts), func(d DirectoryDiff) error {
+		diffs = append(diffs, d)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortDirectoryDiffs(diffs)
+	return diffs, nil
+}
 
+// DirectoriesDiffer reports whether DirectoryDiffs would find any added,
+// removed or changed entry, stopping at the first one.
+func DirectoriesDiffer(ctx context.Context, dir1, dir2 string, opts Options) (bool, error) {
+	err := walkDirectories(ctx, dir1, dir2, briefCompare(ctx, opts), func(d DirectoryDiff) error {
+		if d.Type != "same" && d.Type != "same_dir" {
+			return errDiffer
+		}
+		return nil
+	})
+	if errors.Is(err, errDiffer) {
+		return true, nil
+	}
+	return false, err
+}
+
+// briefCompare compares two files only as far as their first difference.
+func briefCompare(ctx context.Context, opts Options) func(file1, file2 io.Reader, d *DirectoryDiff) error {
+	return func(file1, file2 io.Reader, d *DirectoryDiff) error {
+		differ, err := filesDiffer(ctx, file1, file2, opts)
+		if err != nil {
+			return err
+		}
+		if !differ {
+			d.Type = "same"
+		}
+		return nil
+	}
+}
+
+// walkDirectories passes an entry for every path in dir1 or dir2 to add.
+// Files present in both are handed to compare with a "change" entry for it
+// to fill in. An error from add stops the walk and is returned.
+func walkDirectories(ctx context.Context, dir1, dir2 string, compare func(file1, file2 io.Reader, d *DirectoryDiff) error, add func(DirectoryDiff) error) error {
 	fileMap1 := make(map[string]bool)
 
 	err := filepath.Walk(dir1, func(path1 string, info1 os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		realPath, err := filepath.Rel(dir1, path1)
 		if err != nil {
@@ -40,15 +136,12 @@ func DirectoryDiffs(dir1, dir2 string) ([]DirectoryDiff, error) {
 
 		if err != nil {
 			if info1.IsDir() {
-				diffs = append(diffs, DirectoryDiff{File1: realPath, File2: "", Type: "remove_dir"})
-			} else {
-				diffs = append(diffs, DirectoryDiff{File1: realPath, File2: "", Type: "remove"})
+				return add(DirectoryDiff{File1: realPath, File2: "", Type: "remove_dir"})
 			}
-			return nil
+			return add(DirectoryDiff{File1: realPath, File2: "", Type: "remove"})
 		}
 		if info1.IsDir() && info2.IsDir() {
-			diffs = append(diffs, DirectoryDiff{File1: realPath, File2: realPath, Type: "same_dir"})
-			return nil
+			return add(DirectoryDiff{File1: realPath, File2: realPath, Type: "same_dir"})
 		}
 		if !info1.IsDir() && !info2.IsDir() {
 			file1, err := os.Open(path1)
@@ -63,35 +156,32 @@ func DirectoryDiffs(dir1, dir2 string) ([]DirectoryDiff, error) {
 			}
 			defer file2.Close()
 
-			fileDiffs, binDiff, err := FilesDiff(file1, file2)
-			if err != nil {
+			d := DirectoryDiff{File1: realPath, File2: realPath, Type: "change", ModTime1: info1.ModTime(), ModTime2: info2.ModTime()}
+			if err := compare(file1, file2, &d); err != nil {
 				return fmt.Errorf("diffing files: %w", err)
 			}
-			if binDiff {
-				diffs = append(diffs, DirectoryDiff{File1: realPath, File2: realPath, Type: "change", BinaryDiff: true})
-
-			} else if len(fileDiffs) > 0 {
-				diffs = append(diffs, DirectoryDiff{File1: realPath, File2: realPath, Type: "change", Diffs: fileDiffs})
-			} else {
-				diffs = append(diffs, DirectoryDiff{File1: realPath, File2: realPath, Type: "same"})
-			}
-		} else {
-			diffs = append(diffs, DirectoryDiff{File1: realPath, File2: "", Type: "remove"})
-			diffs = append(diffs, DirectoryDiff{File1: "", File2: realPath, Type: "add"})
-
+			return add(d)
 		}
-
-		return nil
+		if err := add(DirectoryDiff{File1: realPath, File2: "", Type: "remove"}); err != nil {
+			return err
+		}
+		return add(DirectoryDiff{File1: "", File2: realPath, Type: "add"})
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("walking dir1: %w", err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("walking dir1: %w", err)
 	}
 
 	err = filepath.Walk(dir2, func(path2 string, info2 os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		realPath, err := filepath.Rel(dir2, path2)
 		if err != nil {
@@ -102,19 +192,25 @@ func DirectoryDiffs(dir1, dir2 string) ([]DirectoryDiff, error) {
 		}
 		if _, ok := fileMap1[realPath]; !ok {
 			if info2.IsDir() {
-				diffs = append(diffs, DirectoryDiff{File1: "", File2: realPath, Type: "add_dir"})
-			} else {
-				diffs = append(diffs, DirectoryDiff{File1: "", File2: realPath, Type: "add"})
+				return add(DirectoryDiff{File1: "", File2: realPath, Type: "add_dir"})
 			}
+			return add(DirectoryDiff{File1: "", File2: realPath, Type: "add"})
 		}
 
 		return nil
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("walking dir2: %w", err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("walking dir2: %w", err)
 	}
+	return nil
+}
 
+// sortDirectoryDiffs puts directories before files.
+func sortDirectoryDiffs(diffs []DirectoryDiff) {
 	sort.Slice(diffs, func(i, j int) bool {
 		if (diffs[i].Type == "add_dir" || diffs[i].Type == "remove_dir" || diffs[i].Type == "same_dir") &&
 			!(diffs[j].Type == "add_dir" || diffs[j].Type == "remove_dir" || diffs[j].Type == "same_dir") {
@@ -127,6 +223,4 @@ func DirectoryDiffs(dir1, dir2 string) ([]DirectoryDiff, error) {
 
 		return diffs[i].File1 < diffs[j].File1 || diffs[i].File2 < diffs[j].File2
 	})
-
-	return diffs, nil
 }
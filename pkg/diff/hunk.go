@@ -0,0 +1,121 @@
+package diff
+
+import "fmt"
+
+// Hunk is a run of changes together with the unchanged lines around it.
+// Starts are 1-based line numbers; when a side has no lines in the hunk its
+// start is the line just before the hunk, as in unified diffs.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []Diff
+}
+
+// Header formats the hunk range as a unified diff "@@" line.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+// oldFirst is the first old line of the hunk or, when it has none, the line
+// its additions go before.
+func (h Hunk) oldFirst() int {
+	if h.OldLines == 0 {
+		return h.OldStart + 1
+	}
+	return h.OldStart
+}
+
+// newFirst is oldFirst for the new side.
+func (h Hunk) newFirst() int {
+	if h.NewLines == 0 {
+		return h.NewStart + 1
+	}
+	return h.NewStart
+}
+
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// Hunks groups diffs into hunks keeping up to context unchanged lines on
+// either side of every change. Changes separated by no more than 2*context
+// unchanged lines share a hunk. A negative context keeps every line in a
+// single hunk. Diffs without changes produce no hunks.
+func Hunks(diffs []Diff, context int) []Hunk {
+	if context < 0 {
+		context = len(diffs)
+	}
+
+	// oldBefore[i] and newBefore[i] are the number of the line of each side
+	// preceding diffs[i], taken from the line numbers so that dropped lines
+	// do not shift later hunks. oldCount and newCount count the lines each
+	// side has in diffs[:i].
+	oldBefore := make([]int, len(diffs)+1)
+	newBefore := make([]int, len(diffs)+1)
+	oldCount := make([]int, len(diffs)+1)
+	newCount := make([]int, len(diffs)+1)
+	lastOld, lastNew := 0, 0
+	for i, d := range diffs {
+		oldBefore[i], newBefore[i] = lastOld, lastNew
+		oldCount[i+1], newCount[i+1] = oldCount[i], newCount[i]
+		if d.inOld() {
+			oldCount[i+1]++
+			lastOld = max(d.OldLine, lastOld+1)
+			oldBefore[i] = lastOld - 1
+		}
+		if d.inNew() {
+			newCount[i+1]++
+			lastNew = max(d.NewLine, lastNew+1)
+			newBefore[i] = lastNew - 1
+		}
+	}
+	oldBefore[len(diffs)], newBefore[len(diffs)] = lastOld, lastNew
+
+	var hunks []Hunk
+	for i := 0; i < len(diffs); {
+		if diffs[i].Type == "same" {
+			i++
+			continue
+		}
+
+		start := max(i-context, 0)
+		end := i
+		for {
+			for end < len(diffs) && diffs[end].Type != "same" {
+				end++
+			}
+			next := end
+			for next < len(diffs) && diffs[next].Type == "same" {
+				next++
+			}
+			if next < len(diffs) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			end = min(end+context, len(diffs))
+			break
+		}
+
+		h := Hunk{
+			OldStart: oldBefore[start],
+			OldLines: oldCount[end] - oldCount[start],
+			NewStart: newBefore[start],
+			NewLines: newCount[end] - newCount[start],
+			Lines:    diffs[start:end],
+		}
+		if h.OldLines > 0 {
+			h.OldStart++
+		}
+		if h.NewLines > 0 {
+			h.NewStart++
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+	return hunks
+}
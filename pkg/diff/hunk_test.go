@@ -0,0 +1,97 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHunks(t *testing.T) {
+	lines := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, " ")
+	}
+
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		context int
+		want    []string // hunk headers
+		lines   []int    // lines per hunk
+	}{
+		{
+			name:    "identical",
+			old:     "a b c",
+			new:     "a b c",
+			context: 3,
+			want:    nil,
+		},
+		{
+			name:    "single change trims context",
+			old:     "1 2 3 4 5 6 7 8 9",
+			new:     "1 2 3 4 x 6 7 8 9",
+			context: 1,
+			want:    []string{"@@ -4,3 +4,3 @@"},
+			lines:   []int{4},
+		},
+		{
+			name:    "close changes merge",
+			old:     "1 2 3 4 5 6 7 8 9",
+			new:     "1 x 3 4 5 y 7 8 9",
+			context: 2,
+			want:    []string{"@@ -1,8 +1,8 @@"},
+			lines:   []int{10},
+		},
+		{
+			name:    "distant changes split",
+			old:     "1 2 3 4 5 6 7 8 9",
+			new:     "1 x 3 4 5 6 7 y 9",
+			context: 1,
+			want:    []string{"@@ -1,3 +1,3 @@", "@@ -7,3 +7,3 @@"},
+			lines:   []int{4, 4},
+		},
+		{
+			name:    "zero context addition",
+			old:     "1 2 3",
+			new:     "1 2 x 3",
+			context: 0,
+			want:    []string{"@@ -2,0 +3 @@"},
+			lines:   []int{1},
+		},
+		{
+			name:    "removal of everything",
+			old:     "1 2",
+			new:     "",
+			context: 3,
+			want:    []string{"@@ -1,2 +0,0 @@"},
+			lines:   []int{2},
+		},
+		{
+			name:    "negative context keeps everything",
+			old:     "1 2 3 4 5 6 7 8 9",
+			new:     "1 2 3 4 x 6 7 8 9",
+			context: -1,
+			want:    []string{"@@ -1,9 +1,9 @@"},
+			lines:   []int{10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks := Hunks(LineByLine(lines(tt.old), lines(tt.new)), tt.context)
+			if len(hunks) != len(tt.want) {
+				t.Fatalf("Hunks() returned %d hunks, want %d: %v", len(hunks), len(tt.want), hunks)
+			}
+			for i, h := range hunks {
+				if got := h.Header(); got != tt.want[i] {
+					t.Errorf("hunk %d header = %q, want %q", i, got, tt.want[i])
+				}
+				if len(h.Lines) != tt.lines[i] {
+					t.Errorf("hunk %d has %d lines, want %d", i, len(h.Lines), tt.lines[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package diff
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Span is a changed byte range [Start, End) within a line.
+type Span struct {
+	Start int
+	End   int
+}
+
+// pairSimilarity is the share of tokens two lines must have in common to be
+// shown as one changed line rather than a removal and an addition.
+const pairSimilarity = 0.4
+
+// pairCells is the largest number of removed and added line combinations
+// PairChanges weighs against each other in a run. Beyond it, the i-th
+// removal is only ever paired with the i-th addition.
+const pairCells = 1 << 16
+
+// PairChanges turns removed lines that are followed by similar added lines
+// into "change" entries. A change carries the new text in Line, the old
+// text in OldText and both line numbers. Each run of changes is paired so
+// that the total similarity of its pairs is highest while both sides keep
+// their order. Unpaired lines keep their type, removals before additions
+// between two changes.
+func PairChanges(diffs []Diff) []Diff {
+	var paired []Diff
+	for i := 0; i < len(diffs); {
+		if diffs[i].Type != "remove" {
+			paired = append(paired, diffs[i])
+			i++
+			continue
+		}
+		removeEnd := i
+		for removeEnd < len(diffs) && diffs[removeEnd].Type == "remove" {
+			removeEnd++
+		}
+		addEnd := removeEnd
+		for addEnd < len(diffs) && diffs[addEnd].Type == "add" {
+			addEnd++
+		}
+		removed, added := diffs[i:removeEnd], diffs[removeEnd:addEnd]
+
+		r, a := 0, 0
+		for _, p := range linePairs(removed, added) {
+			paired = append(paired, removed[r:p[0]]...)
+			paired = append(paired, added[a:p[1]]...)
+			paired = append(paired, Diff{
+				Line:      added[p[1]].Line,
+				Type:      "change",
+				OldLine:   removed[p[0]].OldLine,
+				NewLine:   added[p[1]].NewLine,
+				NoNewline: added[p[1]].NoNewline,
+				OldText:   removed[p[0]].Line,
+			})
+			r, a = p[0]+1, p[1]+1
+		}
+		paired = append(paired, removed[r:]...)
+		paired = append(paired, added[a:]...)
+		i = addEnd
+	}
+	return paired
+}
+
+// linePairs returns the indexes of the removed and added lines to pair, in
+// order on both sides.
+func linePairs(removed, added []Diff) [][2]int {
+	var pairs [][2]int
+	if len(removed)*len(added) > pairCells {
+		for k := range min(len(removed), len(added)) {
+			if pairScore(removed[k], added[k]) >= pairSimilarity {
+				pairs = append(pairs, [2]int{k, k})
+			}
+		}
+		return pairs
+	}
+
+	// best[i][j] is the highest total similarity of the pairs among
+	// removed[i:] and added[j:].
+	n, m := len(removed), len(added)
+	sim := make([][]float64, n)
+	best := make([][]float64, n+1)
+	for i := range best {
+		best[i] = make([]float64, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		sim[i] = make([]float64, m)
+		for j := m - 1; j >= 0; j-- {
+			best[i][j] = max(best[i+1][j], best[i][j+1])
+			if s := pairScore(removed[i], added[j]); s >= pairSimilarity {
+				sim[i][j] = s
+				best[i][j] = max(best[i][j], s+best[i+1][j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case sim[i][j] > 0 && best[i][j] == sim[i][j]+best[i+1][j+1]:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case best[i][j] == best[i+1][j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
+
+// pairScore is the similarity of a removed and an added line, or zero when
+// only one of them lacks a trailing newline, which a change cannot show.
+func pairScore(removed, added Diff) float64 {
+	if removed.NoNewline != added.NoNewline {
+		return 0
+	}
+	return similarity(removed.Line, added.Line)
+}
+
+// similarity is the share of word tokens the two lines have in common.
+// White space and punctuation only count for lines without any words, as
+// they would make most lines of code look alike.
+func similarity(old, new string) float64 {
+	a, b := wordTokens(old), wordTokens(new)
+	if len(a)+len(b) == 0 {
+		a, b = nonSpaceTokens(old), nonSpaceTokens(new)
+	}
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	common := 0
+	for _, d := range myers(a, b) {
+		if d.Type == "same" {
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
+
+func wordTokens(s string) []string {
+	var words []string
+	for _, t := range tokenize(s, "word") {
+		if r, _ := utf8.DecodeRuneInString(t); runeClass(r) == 1 {
+			words = append(words, t)
+		}
+	}
+	return words
+}
+
+func nonSpaceTokens(s string) []string {
+	var tokens []string
+	for _, t := range tokenize(s, "word") {
+		if r, _ := utf8.DecodeRuneInString(t); runeClass(r) != 2 {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// IntraLine diffs two versions of a line and returns the spans of old that
+// were removed and the spans of new that were added. Level "char" compares
+// rune by rune; anything else compares words, runs of whitespace and single
+// punctuation characters.
+func IntraLine(old, new, level string) (oldSpans, newSpans []Span) {
+	a, b := tokenize(old, level), tokenize(new, level)
+	oldPos, newPos := 0, 0
+	for _, d := range myers(a, b) {
+		switch d.Type {
+		case "same":
+			oldPos += len(d.Line)
+			newPos += len(d.Line)
+		case "remove":
+			oldSpans = appendSpan(oldSpans, oldPos, oldPos+len(d.Line))
+			oldPos += len(d.Line)
+		case "add":
+			newSpans = appendSpan(newSpans, newPos, newPos+len(d.Line))
+			newPos += len(d.Line)
+		}
+	}
+	return oldSpans, newSpans
+}
+
+// appendSpan adds [start, end) to spans, merging it into the last span when
+// the two touch.
+func appendSpan(spans []Span, start, end int) []Span {
+	if n := len(spans); n > 0 && spans[n-1].End == start {
+		spans[n-1].End = end
+		return spans
+	}
+	return append(spans, Span{Start: start, End: end})
+}
+
+func tokenize(s, level string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		end := i + size
+		if level != "char" {
+			class := runeClass(r)
+			for class != 0 && end < len(s) {
+				next, nextSize := utf8.DecodeRuneInString(s[end:])
+				if runeClass(next) != class {
+					break
+				}
+				end += nextSize
+			}
+		}
+		tokens = append(tokens, s[i:end])
+		i = end
+	}
+	return tokens
+}
+
+// runeClass groups runes that form a single word token. Punctuation gets
+// class 0 and always stands alone.
+func runeClass(r rune) int {
+	switch {
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+		return 1
+	case unicode.IsSpace(r):
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPairChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		lines1 []string
+		lines2 []string
+		want   []Diff
+	}{
+		{
+			name:   "unpaired removals before additions",
+			lines1: []string{"a", "the quick brown fox", "totally different", "gone", "z"},
+			lines2: []string{"a", "the slow brown fox", "nothing alike here", "z"},
+			want: []Diff{
+				{Line: "a", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "the slow brown fox", Type: "change", OldLine: 2, NewLine: 2, OldText: "the quick brown fox"},
+				{Line: "totally different", Type: "remove", OldLine: 3},
+				{Line: "gone", Type: "remove", OldLine: 4},
+				{Line: "nothing alike here", Type: "add", NewLine: 3},
+				{Line: "z", Type: "same", OldLine: 5, NewLine: 4},
+			},
+		},
+		{
+			name:   "best pair rather than first",
+			lines1: []string{"foo := 1", "bar := compute(x)"},
+			lines2: []string{"bar := compute(y)"},
+			want: []Diff{
+				{Line: "foo := 1", Type: "remove", OldLine: 1},
+				{Line: "bar := compute(y)", Type: "change", OldLine: 2, NewLine: 1, OldText: "bar := compute(x)"},
+			},
+		},
+		{
+			name:   "lines between pairs",
+			lines1: []string{"one two", "gone", "three four"},
+			lines2: []string{"one 2", "new", "3 four"},
+			want: []Diff{
+				{Line: "one 2", Type: "change", OldLine: 1, NewLine: 1, OldText: "one two"},
+				{Line: "gone", Type: "remove", OldLine: 2},
+				{Line: "new", Type: "add", NewLine: 2},
+				{Line: "3 four", Type: "change", OldLine: 3, NewLine: 3, OldText: "three four"},
+			},
+		},
+		{
+			name:   "white space and punctuation only count without words",
+			lines1: []string{"a := b", "})"},
+			lines2: []string{"c := d", "}"},
+			want: []Diff{
+				{Line: "a := b", Type: "remove", OldLine: 1},
+				{Line: "c := d", Type: "add", NewLine: 1},
+				{Line: "}", Type: "change", OldLine: 2, NewLine: 2, OldText: "})"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PairChanges(LineByLine(tt.lines1, tt.lines2)); !diffsEqual(got, tt.want) {
+				t.Errorf("PairChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntraLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		level   string
+		wantOld []Span
+		wantNew []Span
+	}{
+		{
+			name:    "one word",
+			old:     "the quick brown fox",
+			new:     "the slow brown fox",
+			level:   "word",
+			wantOld: []Span{{Start: 4, End: 9}},
+			wantNew: []Span{{Start: 4, End: 8}},
+		},
+		{
+			name:    "words merge into one span",
+			old:     "f(a, b)",
+			new:     "f(x, y, b)",
+			level:   "word",
+			wantOld: []Span{{Start: 2, End: 3}},
+			wantNew: []Span{{Start: 2, End: 6}},
+		},
+		{
+			name:    "characters",
+			old:     "colour",
+			new:     "color",
+			level:   "char",
+			wantOld: []Span{{Start: 4, End: 5}},
+			wantNew: nil,
+		},
+		{
+			name:    "multibyte runes",
+			old:     "naïve",
+			new:     "naive",
+			level:   "char",
+			wantOld: []Span{{Start: 2, End: 4}},
+			wantNew: []Span{{Start: 2, End: 3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOld, gotNew := IntraLine(tt.old, tt.new, tt.level)
+			if !reflect.DeepEqual(gotOld, tt.wantOld) || !reflect.DeepEqual(gotNew, tt.wantNew) {
+				t.Errorf("IntraLine() = %v, %v, want %v, %v", gotOld, gotNew, tt.wantOld, tt.wantNew)
+			}
+		})
+	}
+}
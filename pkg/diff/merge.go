@@ -0,0 +1,225 @@
+package diff
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// MergeRegion is a stretch of a three-way merge. Type is "same" when no
+// side changed it, "ours" or "theirs" when only that side did, "both" when
+// both made the same change and "conflict" when they made different ones.
+// BaseLine, OursLine and TheirsLine are the 1-based line numbers at which
+// the region starts in each input. A conflict settled with Merge.Resolve
+// has Resolved set and is merged as Resolution.
+type MergeRegion struct {
+	Type       string
+	Base       []string
+	Ours       []string
+	Theirs     []string
+	BaseLine   int
+	OursLine   int
+	TheirsLine int
+	Resolved   bool
+	Resolution []string
+}
+
+// Merge is the result of Merge3.
+type Merge struct {
+	Regions []MergeRegion
+
+	base, ours, theirs text
+}
+
+// Conflicts returns the number of conflicting regions not yet resolved.
+func (m Merge) Conflicts() int {
+	n := 0
+	for _, r := range m.Regions {
+		if r.Type == "conflict" && !r.Resolved {
+			n++
+		}
+	}
+	return n
+}
+
+// Resolve settles the conflict in region i with lines.
+func (m *Merge) Resolve(i int, lines []string) {
+	m.Regions[i].Resolved = true
+	m.Regions[i].Resolution = lines
+}
+
+// Unresolve undoes Resolve.
+func (m *Merge) Unresolve(i int) {
+	m.Regions[i].Resolved = false
+	m.Regions[i].Resolution = nil
+}
+
+// Merge3 combines the changes ours and theirs made to base, as diff3 -m
+// does. Lines are compared exactly; of opts only Algorithm and MaxCost are
+// used.
+func Merge3(base, ours, theirs io.Reader, opts Options) (Merge, error) {
+	var m Merge
+	var err error
+	if m.base, err = readText(base); err != nil {
+		return Merge{}, err
+	}
+	if m.ours, err = readText(ours); err != nil {
+		return Merge{}, err
+	}
+	if m.theirs, err = readText(theirs); err != nil {
+		return Merge{}, err
+	}
+
+	exact := Options{Algorithm: opts.Algorithm, MaxCost: opts.MaxCost}
+	baseKeys, oursKeys, theirsKeys := m.base.keys(exact), m.ours.keys(exact), m.theirs.keys(exact)
+	toOurs, err := matches(exact, baseKeys, oursKeys)
+	if err != nil {
+		return Merge{}, err
+	}
+	toTheirs, err := matches(exact, baseKeys, theirsKeys)
+	if err != nil {
+		return Merge{}, err
+	}
+
+	// i, j and k walk base, ours and theirs. Base lines kept by both sides
+	// are the sync points; everything between two of them is one region.
+	i, j, k := 0, 0, 0
+	for i < len(baseKeys) || j < len(oursKeys) || k < len(theirsKeys) {
+		if i < len(baseKeys) && toOurs[i] == j && toTheirs[i] == k {
+			m.add(MergeRegion{Type: "same", Base: m.base.lines[i : i+1 : i+1], Ours: m.ours.lines[j : j+1 : j+1], Theirs: m.theirs.lines[k : k+1 : k+1]}, i, j, k)
+			i, j, k = i+1, j+1, k+1
+			continue
+		}
+
+		next, oursEnd, theirsEnd := i, len(oursKeys), len(theirsKeys)
+		for ; next < len(baseKeys); next++ {
+			if toOurs[next] >= 0 && toTheirs[next] >= 0 {
+				oursEnd, theirsEnd = toOurs[next], toTheirs[next]
+				break
+			}
+		}
+		// Full slice expressions keep appends to a region from overwriting
+		// the inputs.
+		r := MergeRegion{Base: m.base.lines[i:next:next], Ours: m.ours.lines[j:oursEnd:oursEnd], Theirs: m.theirs.lines[k:theirsEnd:theirsEnd]}
+		baseChunk, oursChunk, theirsChunk := baseKeys[i:next], oursKeys[j:oursEnd], theirsKeys[k:theirsEnd]
+		switch {
+		case equalLines(oursChunk, baseChunk):
+			r.Type = "theirs"
+		case equalLines(theirsChunk, baseChunk):
+			r.Type = "ours"
+		case equalLines(oursChunk, theirsChunk):
+			r.Type = "both"
+		default:
+			r.Type = "conflict"
+		}
+		m.add(r, i, j, k)
+		i, j, k = next, oursEnd, theirsEnd
+	}
+	return m, nil
+}
+
+// add appends r, which starts at the given 0-based positions, merging it
+// into the previous region when both are unchanged.
+func (m *Merge) add(r MergeRegion, i, j, k int) {
+	if n := len(m.Regions); n > 0 && r.Type == "same" && m.Regions[n-1].Type == "same" {
+		last := &m.Regions[n-1]
+		last.Base = m.base.lines[last.BaseLine-1 : i+1 : i+1]
+		last.Ours = m.ours.lines[last.OursLine-1 : j+1 : j+1]
+		last.Theirs = m.theirs.lines[last.TheirsLine-1 : k+1 : k+1]
+		return
+	}
+	r.BaseLine, r.OursLine, r.TheirsLine = i+1, j+1, k+1
+	m.Regions = append(m.Regions, r)
+}
+
+// matches maps every line of a kept by the edit script from a to b to its
+// index in b, and every other line to -1.
+func matches(opts Options, a, b []string) ([]int, error) {
+	diffs, err := opts.diff(context.Background(), a, b)
+	if err != nil {
+		return nil, err
+	}
+	to := make([]int, len(a))
+	for _, d := range diffs {
+		switch d.Type {
+		case "same":
+			to[d.OldLine-1] = d.NewLine - 1
+		case "remove":
+			to[d.OldLine-1] = -1
+		}
+	}
+	return to, nil
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MergeStyle controls how WriteMerge marks conflicts. The labels follow the
+// conflict markers, as file names do in git.
+type MergeStyle struct {
+	OursLabel   string
+	BaseLabel   string
+	TheirsLabel string
+	// Diff3 also shows the base version of conflicting regions.
+	Diff3 bool
+}
+
+// WriteMerge writes the merged text. Unresolved conflicts are written with
+// git-style <<<<<<<, ======= and >>>>>>> markers around both versions.
+func WriteMerge(w io.Writer, m Merge, style MergeStyle) error {
+	var b strings.Builder
+	marker := func(s, label string) {
+		b.WriteString(s)
+		if label != "" {
+			b.WriteString(" " + label)
+		}
+		b.WriteString("\n")
+	}
+	for _, r := range m.Regions {
+		switch r.Type {
+		case "theirs":
+			writeMergeLines(&b, m.theirs, r.Theirs, r.TheirsLine, false)
+		case "conflict":
+			if r.Resolved {
+				for _, line := range r.Resolution {
+					b.WriteString(line + "\n")
+				}
+				continue
+			}
+			marker("<<<<<<<", style.OursLabel)
+			writeMergeLines(&b, m.ours, r.Ours, r.OursLine, true)
+			if style.Diff3 {
+				marker("|||||||", style.BaseLabel)
+				writeMergeLines(&b, m.base, r.Base, r.BaseLine, true)
+			}
+			marker("=======", "")
+			writeMergeLines(&b, m.theirs, r.Theirs, r.TheirsLine, true)
+			marker(">>>>>>>", style.TheirsLabel)
+		default:
+			writeMergeLines(&b, m.ours, r.Ours, r.OursLine, false)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMergeLines writes lines of t starting at line number first. The
+// last line of t keeps its missing newline unless newline forces one, as
+// before a conflict marker.
+func writeMergeLines(b *strings.Builder, t text, lines []string, first int, newline bool) {
+	for i, line := range lines {
+		b.WriteString(line)
+		if newline || !t.missingNewline || first+i != len(t.lines) {
+			b.WriteString("\n")
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      string
+		ours      string
+		theirs    string
+		diff3     bool
+		want      string
+		conflicts int
+	}{
+		{
+			name:   "ours only",
+			base:   "a\nb\nc\n",
+			ours:   "a\nB\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nB\nc\n",
+		},
+		{
+			name:   "theirs only",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nc\nd\n",
+			want:   "a\nb\nc\nd\n",
+		},
+		{
+			name:   "separate changes",
+			base:   "a\nb\nc\nd\ne\n",
+			ours:   "A\nb\nc\nd\ne\n",
+			theirs: "a\nb\nc\nd\nE\n",
+			want:   "A\nb\nc\nd\nE\n",
+		},
+		{
+			name:   "same change on both sides",
+			base:   "a\nb\nc\n",
+			ours:   "a\nx\nc\n",
+			theirs: "a\nx\nc\n",
+			want:   "a\nx\nc\n",
+		},
+		{
+			name:      "conflict",
+			base:      "a\nb\nc\n",
+			ours:      "a\nours\nc\n",
+			theirs:    "a\ntheirs\nc\n",
+			want:      "a\n<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "conflict with base",
+			base:      "a\nb\nc\n",
+			ours:      "a\nours\nc\n",
+			theirs:    "a\ntheirs\nc\n",
+			diff3:     true,
+			want:      "a\n<<<<<<< ours\nours\n||||||| base\nb\n=======\ntheirs\n>>>>>>> theirs\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "different additions at the end",
+			base:      "a\n",
+			ours:      "a\nx\n",
+			theirs:    "a\ny\n",
+			want:      "a\n<<<<<<< ours\nx\n=======\ny\n>>>>>>> theirs\n",
+			conflicts: 1,
+		},
+		{
+			name:      "removed and changed",
+			base:      "a\nb\nc\n",
+			ours:      "a\nc\n",
+			theirs:    "a\nB\nc\n",
+			want:      "a\n<<<<<<< ours\n=======\nB\n>>>>>>> theirs\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:   "missing newline kept",
+			base:   "a\nb\nc\n",
+			ours:   "A\nb\nc\n",
+			theirs: "a\nb\nc",
+			want:   "A\nb\nc",
+		},
+		{
+			name:      "missing newline before a marker",
+			base:      "a\n",
+			ours:      "x",
+			theirs:    "y\n",
+			want:      "<<<<<<< ours\nx\n=======\ny\n>>>>>>> theirs\n",
+			conflicts: 1,
+		},
+		{
+			name:   "empty base",
+			base:   "",
+			ours:   "a\n",
+			theirs: "a\n",
+			want:   "a\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Merge3(strings.NewReader(tt.base), strings.NewReader(tt.ours), strings.NewReader(tt.theirs), Options{})
+			if err != nil {
+				t.Fatalf("Merge3() error = %v", err)
+			}
+			if got := m.Conflicts(); got != tt.conflicts {
+				t.Errorf("Conflicts() = %d, want %d", got, tt.conflicts)
+			}
+			var b strings.Builder
+			style := MergeStyle{OursLabel: "ours", BaseLabel: "base", TheirsLabel: "theirs", Diff3: tt.diff3}
+			if err := WriteMerge(&b, m, style); err != nil {
+				t.Fatalf("WriteMerge() error = %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("WriteMerge() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge3Regions(t *testing.T) {
+	m, err := Merge3(
+		strings.NewReader("a\nb\nc\nd\ne\n"),
+		strings.NewReader("a\nB\nc\nd\nx\n"),
+		strings.NewReader("a\nb\nc\nD\ny\n"),
+		Options{},
+	)
+	if err != nil {
+		t.Fatalf("Merge3() error = %v", err)
+	}
+	want := []MergeRegion{
+		{Type: "same", Base: []string{"a"}, Ours: []string{"a"}, Theirs: []string{"a"}, BaseLine: 1, OursLine: 1, TheirsLine: 1},
+		{Type: "ours", Base: []string{"b"}, Ours: []string{"B"}, Theirs: []string{"b"}, BaseLine: 2, OursLine: 2, TheirsLine: 2},
+		{Type: "same", Base: []string{"c"}, Ours: []string{"c"}, Theirs: []string{"c"}, BaseLine: 3, OursLine: 3, TheirsLine: 3},
+		{Type: "conflict", Base: []string{"d", "e"}, Ours: []string{"d", "x"}, Theirs: []string{"D", "y"}, BaseLine: 4, OursLine: 4, TheirsLine: 4},
+	}
+	if len(m.Regions) != len(want) {
+		t.Fatalf("Merge3() regions = %+v, want %+v", m.Regions, want)
+	}
+	for i, r := range m.Regions {
+		w := want[i]
+		if r.Type != w.Type || !equalLines(r.Base, w.Base) || !equalLines(r.Ours, w.Ours) || !equalLines(r.Theirs, w.Theirs) ||
+			r.BaseLine != w.BaseLine || r.OursLine != w.OursLine || r.TheirsLine != w.TheirsLine {
+			t.Errorf("region %d = %+v, want %+v", i, r, w)
+		}
+	}
+}
+
+func TestMergeResolve(t *testing.T) {
+	m, err := Merge3(
+		strings.NewReader("a\nb\nc\nd\ne\n"),
+		strings.NewReader("a\nB\nc\nD\ne\n"),
+		strings.NewReader("a\nb2\nc\nd2\ne\n"),
+		Options{},
+	)
+	if err != nil {
+		t.Fatalf("Merge3() error = %v", err)
+	}
+	if got := m.Conflicts(); got != 2 {
+		t.Fatalf("Conflicts() = %d, want 2", got)
+	}
+
+	m.Resolve(1, append(m.Regions[1].Ours, m.Regions[1].Theirs...))
+	m.Resolve(3, []string{"edited"})
+	if got := m.Conflicts(); got != 0 {
+		t.Errorf("Conflicts() after resolving = %d, want 0", got)
+	}
+	var b strings.Builder
+	WriteMerge(&b, m, MergeStyle{})
+	if want := "a\nB\nb2\nc\nedited\ne\n"; b.String() != want {
+		t.Errorf("WriteMerge() = %q, want %q", b.String(), want)
+	}
+
+	m.Unresolve(3)
+	if got := m.Conflicts(); got != 1 {
+		t.Errorf("Conflicts() after Unresolve = %d, want 1", got)
+	}
+}
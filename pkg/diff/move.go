@@ -0,0 +1,67 @@
+package diff
+
+import "strings"
+
+// DetectMoves finds blocks of removed lines that reappear unchanged as added
+// lines elsewhere and retypes them as "move_from" and "move_to". Both halves
+// of a block share a MoveID, numbered from 1 in the order the blocks were
+// added. Only blocks with at least minLines non-blank lines count as moved,
+// so stray braces and blank lines are left alone; minLines <= 0 disables
+// detection. The input is not modified.
+func DetectMoves(diffs []Diff, minLines int) []Diff {
+	if minLines <= 0 {
+		return diffs
+	}
+
+	removed := make(map[string][]int)
+	for i, d := range diffs {
+		if d.Type == "remove" {
+			removed[d.Line] = append(removed[d.Line], i)
+		}
+	}
+
+	moved := make([]Diff, len(diffs))
+	copy(moved, diffs)
+	id := 0
+	for j := 0; j < len(moved); j++ {
+		if moved[j].Type != "add" {
+			continue
+		}
+
+		// Removed lines already claimed by an earlier block are
+		// "move_from" by now and stop the match.
+		from, n := 0, 0
+		for _, i := range removed[moved[j].Line] {
+			k := 0
+			for i+k < len(moved) && j+k < len(moved) &&
+				moved[i+k].Type == "remove" && moved[j+k].Type == "add" &&
+				moved[i+k].Line == moved[j+k].Line {
+				k++
+			}
+			if k > n {
+				from, n = i, k
+			}
+		}
+		if nonBlankLines(moved[j:j+n]) < minLines {
+			continue
+		}
+
+		id++
+		for k := 0; k < n; k++ {
+			moved[from+k].Type, moved[from+k].MoveID = "move_from", id
+			moved[j+k].Type, moved[j+k].MoveID = "move_to", id
+		}
+		j += n - 1
+	}
+	return moved
+}
+
+func nonBlankLines(diffs []Diff) int {
+	n := 0
+	for _, d := range diffs {
+		if strings.TrimSpace(d.Line) != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,145 @@
+package diff
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDetectMoves(t *testing.T) {
+	tests := []struct {
+		name     string
+		minLines int
+		want     []Diff
+	}{
+		{
+			name:     "block moved down",
+			minLines: 3,
+			want: []Diff{
+				{Line: "func a() {", Type: "move_from", OldLine: 1, MoveID: 1},
+				{Line: "\treturn 1", Type: "move_from", OldLine: 2, MoveID: 1},
+				{Line: "}", Type: "move_from", OldLine: 3, MoveID: 1},
+				{Line: "x", Type: "same", OldLine: 4, NewLine: 1},
+				{Line: "y", Type: "same", OldLine: 5, NewLine: 2},
+				{Line: "func a() {", Type: "move_to", NewLine: 3, MoveID: 1},
+				{Line: "\treturn 1", Type: "move_to", NewLine: 4, MoveID: 1},
+				{Line: "}", Type: "move_to", NewLine: 5, MoveID: 1},
+			},
+		},
+		{
+			name:     "block too short",
+			minLines: 3,
+			want: []Diff{
+				{Line: "a", Type: "remove", OldLine: 1},
+				{Line: "b", Type: "remove", OldLine: 2},
+				{Line: "x", Type: "same", OldLine: 3, NewLine: 1},
+				{Line: "a", Type: "add", NewLine: 2},
+				{Line: "b", Type: "add", NewLine: 3},
+			},
+		},
+		{
+			name:     "blank lines do not count",
+			minLines: 3,
+			want: []Diff{
+				{Line: "a", Type: "remove", OldLine: 1},
+				{Line: "", Type: "remove", OldLine: 2},
+				{Line: "", Type: "remove", OldLine: 3},
+				{Line: "b", Type: "remove", OldLine: 4},
+				{Line: "x", Type: "same", OldLine: 5, NewLine: 1},
+				{Line: "a", Type: "add", NewLine: 2},
+				{Line: "", Type: "add", NewLine: 3},
+				{Line: "", Type: "add", NewLine: 4},
+				{Line: "b", Type: "add", NewLine: 5},
+			},
+		},
+		{
+			name:     "two blocks swap",
+			minLines: 2,
+			want: []Diff{
+				{Line: "a1", Type: "move_from", OldLine: 1, MoveID: 2},
+				{Line: "a2", Type: "move_from", OldLine: 2, MoveID: 2},
+				{Line: "b1", Type: "move_to", NewLine: 1, MoveID: 1},
+				{Line: "b2", Type: "move_to", NewLine: 2, MoveID: 1},
+				{Line: "x", Type: "same", OldLine: 3, NewLine: 3},
+				{Line: "b1", Type: "move_from", OldLine: 4, MoveID: 1},
+				{Line: "b2", Type: "move_from", OldLine: 5, MoveID: 1},
+				{Line: "a1", Type: "move_to", NewLine: 4, MoveID: 2},
+				{Line: "a2", Type: "move_to", NewLine: 5, MoveID: 2},
+			},
+		},
+		{
+			name:     "partial move keeps the rest",
+			minLines: 3,
+			want: []Diff{
+				{Line: "p", Type: "remove", OldLine: 1},
+				{Line: "q", Type: "move_from", OldLine: 2, MoveID: 1},
+				{Line: "r", Type: "move_from", OldLine: 3, MoveID: 1},
+				{Line: "s", Type: "move_from", OldLine: 4, MoveID: 1},
+				{Line: "x", Type: "same", OldLine: 5, NewLine: 1},
+				{Line: "q", Type: "move_to", NewLine: 2, MoveID: 1},
+				{Line: "r", Type: "move_to", NewLine: 3, MoveID: 1},
+				{Line: "s", Type: "move_to", NewLine: 4, MoveID: 1},
+				{Line: "t", Type: "add", NewLine: 5},
+			},
+		},
+		{
+			name:     "disabled",
+			minLines: 0,
+			want: []Diff{
+				{Line: "a", Type: "remove", OldLine: 1},
+				{Line: "b", Type: "remove", OldLine: 2},
+				{Line: "c", Type: "remove", OldLine: 3},
+				{Line: "x", Type: "same", OldLine: 4, NewLine: 1},
+				{Line: "a", Type: "add", NewLine: 2},
+				{Line: "b", Type: "add", NewLine: 3},
+				{Line: "c", Type: "add", NewLine: 4},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectMoves(unmoved(tt.want), tt.minLines); !diffsEqual(got, tt.want) {
+				t.Errorf("DetectMoves() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// unmoved turns the moved lines of diffs back into removals and additions.
+func unmoved(diffs []Diff) []Diff {
+	plain := make([]Diff, len(diffs))
+	for i, d := range diffs {
+		switch d.Type {
+		case "move_from":
+			d.Type = "remove"
+		case "move_to":
+			d.Type = "add"
+		}
+		d.MoveID = 0
+		plain[i] = d
+	}
+	return plain
+}
+
+func TestHunksWithMoves(t *testing.T) {
+	var diffs []Diff
+	for i, line := range []string{"a", "b", "c"} {
+		diffs = append(diffs, Diff{Line: line, Type: "move_from", OldLine: i + 1, MoveID: 1})
+	}
+	for i := 1; i <= 8; i++ {
+		diffs = append(diffs, Diff{Line: strconv.Itoa(i), Type: "same", OldLine: i + 3, NewLine: i})
+	}
+	for i, line := range []string{"a", "b", "c"} {
+		diffs = append(diffs, Diff{Line: line, Type: "move_to", NewLine: i + 9, MoveID: 1})
+	}
+
+	var headers []string
+	for _, h := range Hunks(diffs, 1) {
+		headers = append(headers, h.Header())
+	}
+	want := []string{"@@ -1,4 +1 @@", "@@ -11 +8,4 @@"}
+	if strings.Join(headers, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Hunks() headers = %q, want %q", headers, want)
+	}
+}
@@ -0,0 +1,234 @@
+package diff
+
+import "context"
+
+// myers computes a minimal edit script turning a into b using the O(ND)
+// algorithm from Eugene Myers' "An O(ND) Difference Algorithm and Its
+// Variations". Removals are emitted before additions within a change.
+func myers(a, b []string) []Diff {
+	return newSearch(context.Background(), 0).myers(a, b)
+}
+
+func (s *search) myers(a, b []string) []Diff {
+	return withCommonEnds(a, b, s.shortestEdit)
+}
+
+// commonEnds returns the lengths of the common prefix and suffix of a and b.
+// The two never overlap. They never take part in an edit script, so every
+// algorithm strips them to keep the search space small.
+func commonEnds(a, b []string) (prefix, suffix int) {
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return prefix, suffix
+}
+
+// traceLimit is the edit distance up to which a search without a cost
+// budget keeps the trace of editPrefix, whose size grows with its square.
+// Scripts that take more edits are found by bisect in linear space.
+const traceLimit = 1024
+
+// shortestEdit finds the edit script one stretch at a time, see
+// editPrefix. Without a cost budget the first stretch is the whole script,
+// unless it takes more than traceLimit edits and bisect finds it instead.
+func (s *search) shortestEdit(a, b []string) []Diff {
+	budget := s.maxCost
+	if budget <= 0 {
+		budget = traceLimit
+	}
+	var diffs []Diff
+	for {
+		part, x, y := s.editPrefix(a, b, budget)
+		if s.maxCost <= 0 && (x < len(a) || y < len(b)) {
+			return removalsFirst(s.bisect(a, b, nil))
+		}
+		diffs = append(diffs, part...)
+		if x == len(a) && y == len(b) {
+			return diffs
+		}
+		a, b = a[x:], b[y:]
+	}
+}
+
+// editPrefix runs the greedy forward search over edit distance d and then
+// walks the recorded frontiers backwards to recover the path. Once d
+// exceeds budget it settles for the path that got furthest so far
+// and returns the script for a[:x] and b[:y] only. When the search is
+// cancelled the rest of a and b is returned as changed.
+func (s *search) editPrefix(a, b []string, budget int) (diffs []Diff, x, y int) {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil, 0, 0
+	}
+
+	// v[offset+k] holds the furthest x reached on diagonal k = x - y.
+	offset := max
+	v := make([]int, 2*max+2)
+	// trace[d] is a copy of v for diagonals -d..d after step d.
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		if s.cancelled() {
+			return changedLines(a, b), n, m
+		}
+		if d > budget {
+			// Take the furthest point inside the grid reached after d-1
+			// edits, on the diagonals trace[d-1] covers.
+			x, y := 0, 0
+			for k := -d + 1; k < d; k += 2 {
+				kx := v[offset+k]
+				if ky := kx - k; kx <= n && ky >= 0 && ky <= m && kx+ky > x+y {
+					x, y = kx, ky
+				}
+			}
+			return backtrack(trace, a[:x], b[:y], d-1), x, y
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // down: insertion
+			} else {
+				x = v[offset+k-1] + 1 // right: deletion
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, d), n, m
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+	return nil, n, m // unreachable: d == n+m always reaches the end
+}
+
+// bisect appends a minimal edit script turning a into b to diffs. It splits
+// the problem at the middle snake of an optimal path and recurses on both
+// sides, the linear space refinement of section 4b of Myers' paper. When
+// the search is cancelled the rest of a and b is appended as changed.
+func (s *search) bisect(a, b []string, diffs []Diff) []Diff {
+	prefix, suffix := commonEnds(a, b)
+	diffs = append(diffs, sameLines(a[:prefix])...)
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if x, y, u, v, ok := s.middleSnake(midA, midB); ok {
+		diffs = s.bisect(midA[:x], midB[:y], diffs)
+		diffs = append(diffs, sameLines(midA[x:u])...)
+		diffs = s.bisect(midA[u:], midB[v:], diffs)
+	} else {
+		diffs = append(diffs, changedLines(midA, midB)...)
+	}
+	return append(diffs, sameLines(a[len(a)-suffix:])...)
+}
+
+// middleSnake runs the greedy search forwards from the start and backwards
+// from the end of a and b at once until the two meet. The snake where they
+// do, from (x, y) to (u, v), lies on an optimal path. ok is false when one
+// of a and b is empty, as there is nothing left to split, or when the
+// search is cancelled.
+func (s *search) middleSnake(a, b []string) (x, y, u, v int, ok bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return 0, 0, 0, 0, false
+	}
+	delta := n - m
+	odd := delta%2 != 0
+	// forward[offset+k] holds the furthest x reached from the start on
+	// diagonal k = x - y, backward[offset+k] the furthest distance from the
+	// end on diagonal k of the reversed inputs, that is delta - k.
+	offset := (n+m+1)/2 + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for d := 0; d < offset; d++ {
+		if s.cancelled() {
+			return 0, 0, 0, 0, false
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			if kr := delta - k; odd && kr >= -(d-1) && kr <= d-1 && x+backward[offset+kr] >= n {
+				return startX, startY, x, y, true
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			if kf := delta - k; !odd && kf >= -d && kf <= d && x+forward[offset+kf] >= n {
+				return n - x, m - y, n - startX, m - startY, true
+			}
+		}
+	}
+	return 0, 0, 0, 0, false // unreachable: the searches meet by d == offset-1
+}
+
+func backtrack(trace [][]int, a, b []string, d int) []Diff {
+	x, y := len(a), len(b)
+	var reversed []Diff
+
+	for ; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Diff{Line: a[x-1], Type: "same"})
+			x--
+			y--
+		}
+		if prevK == k+1 {
+			reversed = append(reversed, Diff{Line: b[y-1], Type: "add"})
+		} else {
+			reversed = append(reversed, Diff{Line: a[x-1], Type: "remove"})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, Diff{Line: a[x-1], Type: "same"})
+		x--
+		y--
+	}
+
+	diffs := make([]Diff, len(reversed))
+	for i, d := range reversed {
+		diffs[len(reversed)-1-i] = d
+	}
+	return diffs
+}
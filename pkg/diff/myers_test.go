@@ -0,0 +1,211 @@
+package diff
+
+import (
+	"context"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLineByLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		lines1 []string
+		lines2 []string
+		want   []Diff
+	}{
+		{
+			name:   "insert in the middle",
+			lines1: []string{"a", "b", "d", "e"},
+			lines2: []string{"a", "b", "c", "d", "e"},
+			want: []Diff{
+				{Line: "a", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "b", Type: "same", OldLine: 2, NewLine: 2},
+				{Line: "c", Type: "add", NewLine: 3},
+				{Line: "d", Type: "same", OldLine: 3, NewLine: 4},
+				{Line: "e", Type: "same", OldLine: 4, NewLine: 5},
+			},
+		},
+		{
+			name:   "insert line that sorts before the rest",
+			lines1: []string{"x", "y", "z"},
+			lines2: []string{"x", "a", "y", "z"},
+			want: []Diff{
+				{Line: "x", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "a", Type: "add", NewLine: 2},
+				{Line: "y", Type: "same", OldLine: 2, NewLine: 3},
+				{Line: "z", Type: "same", OldLine: 3, NewLine: 4},
+			},
+		},
+		{
+			name:   "delete in the middle",
+			lines1: []string{"a", "b", "c"},
+			lines2: []string{"a", "c"},
+			want: []Diff{
+				{Line: "a", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "b", Type: "remove", OldLine: 2},
+				{Line: "c", Type: "same", OldLine: 3, NewLine: 2},
+			},
+		},
+		{
+			name:   "replace everything",
+			lines1: []string{"a", "b"},
+			lines2: []string{"c"},
+			want: []Diff{
+				{Line: "a", Type: "remove", OldLine: 1},
+				{Line: "b", Type: "remove", OldLine: 2},
+				{Line: "c", Type: "add", NewLine: 1},
+			},
+		},
+		{
+			name:   "only additions",
+			lines1: nil,
+			lines2: []string{"a", "b"},
+			want: []Diff{
+				{Line: "a", Type: "add", NewLine: 1},
+				{Line: "b", Type: "add", NewLine: 2},
+			},
+		},
+		{
+			name:   "only removals",
+			lines1: []string{"a", "b"},
+			lines2: nil,
+			want: []Diff{
+				{Line: "a", Type: "remove", OldLine: 1},
+				{Line: "b", Type: "remove", OldLine: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LineByLine(tt.lines1, tt.lines2)
+			if !diffsEqual(got, tt.want) {
+				t.Errorf("LineByLine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineByLineMinimalAndApplies(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []string{"a", "b", "c", "{", "}", ""}
+
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(12))
+		for i := range lines {
+			lines[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return lines
+	}
+
+	for i := 0; i < 2000; i++ {
+		lines1, lines2 := randomLines(), randomLines()
+		got := LineByLine(lines1, lines2)
+
+		old, new := applyDiffs(got)
+		if strings.Join(old, "\n") != strings.Join(lines1, "\n") {
+			t.Fatalf("LineByLine(%q, %q): old side = %q", lines1, lines2, old)
+		}
+		if strings.Join(new, "\n") != strings.Join(lines2, "\n") {
+			t.Fatalf("LineByLine(%q, %q): applying script gives %q", lines1, lines2, new)
+		}
+
+		edits := 0
+		for _, d := range got {
+			if d.Type != "same" {
+				edits++
+			}
+		}
+		if want := len(lines1) + len(lines2) - 2*lcsLength(lines1, lines2); edits != want {
+			t.Fatalf("LineByLine(%q, %q) uses %d edits, minimal is %d", lines1, lines2, edits, want)
+		}
+	}
+}
+
+// applyDiffs reconstructs both inputs from an edit script.
+func applyDiffs(diffs []Diff) (old, new []string) {
+	for _, d := range diffs {
+		switch d.Type {
+		case "same":
+			old = append(old, d.Line)
+			new = append(new, d.Line)
+		case "remove":
+			old = append(old, d.Line)
+		case "add":
+			new = append(new, d.Line)
+		}
+	}
+	return old, new
+}
+
+// lcsLength is the textbook dynamic-programming longest common subsequence.
+func lcsLength(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+func TestBisectMinimalAndApplies(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	alphabet := []string{"a", "b", "c", "{", "}", ""}
+
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return lines
+	}
+
+	for i := 0; i < 2000; i++ {
+		lines1, lines2 := randomLines(), randomLines()
+		got := removalsFirst(newSearch(context.Background(), 0).bisect(lines1, lines2, nil))
+
+		old, new := applyDiffs(got)
+		if strings.Join(old, "\n") != strings.Join(lines1, "\n") || strings.Join(new, "\n") != strings.Join(lines2, "\n") {
+			t.Fatalf("bisect(%q, %q) does not reproduce its inputs: %q, %q", lines1, lines2, old, new)
+		}
+		edits := 0
+		for j, d := range got {
+			if d.Type != "same" {
+				edits++
+			}
+			if j > 0 && d.Type == "remove" && got[j-1].Type == "add" {
+				t.Fatalf("bisect(%q, %q) = %v adds before it removes", lines1, lines2, got)
+			}
+		}
+		if want := len(lines1) + len(lines2) - 2*lcsLength(lines1, lines2); edits != want {
+			t.Fatalf("bisect(%q, %q) uses %d edits, minimal is %d", lines1, lines2, edits, want)
+		}
+	}
+}
+
+func TestLineByLineRewriteMemory(t *testing.T) {
+	// A trace of every frontier would take about (2n)² ints, 512 MB here.
+	n := 4000
+	lines1, lines2 := strings.Split(unrelatedLines("a", n), "\n"), strings.Split(unrelatedLines("b", n), "\n")
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	diffs := LineByLine(lines1, lines2)
+	runtime.ReadMemStats(&after)
+
+	if edits := len(diffs) - 1; edits != 2*n {
+		t.Errorf("LineByLine() uses %d edits, want %d", edits, 2*n)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 64<<20 {
+		t.Errorf("LineByLine() allocated %d MB", alloc>>20)
+	}
+}
@@ -0,0 +1,210 @@
+package diff
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Options controls how two inputs are compared. The zero value compares
+// lines exactly using Myers. The Ignore options only affect which lines
+// match; diffs always carry the original text.
+type Options struct {
+	// Algorithm computes the edit script; nil means Myers.
+	Algorithm Differ
+	// MaxCost makes the built-in algorithms settle for a quicker,
+	// non-minimal edit script once the edit distance of a region exceeds
+	// it. Zero always searches for a minimal script, which can take
+	// quadratic time on inputs with many changes.
+	MaxCost int
+
+	// IgnoreAllSpace ignores all white space (diff -w).
+	IgnoreAllSpace bool
+	// IgnoreSpaceChange ignores changes in the amount of white space
+	// (diff -b).
+	IgnoreSpaceChange bool
+	// IgnoreTrailingSpace ignores white space at the end of lines (diff -Z).
+	IgnoreTrailingSpace bool
+	// IgnoreBlankLines drops hunks whose changes only add or remove blank
+	// lines (diff -B). Hunks that also change other lines are kept whole.
+	IgnoreBlankLines bool
+	// Context is the number of unchanged lines the output shows around
+	// each change, which decides the hunks IgnoreBlankLines looks at, see
+	// Hunks.
+	Context int
+	// StripTrailingCR ignores a carriage return at the end of lines
+	// (diff --strip-trailing-cr).
+	StripTrailingCR bool
+	// IgnoreMatching treats all lines matching any of the expressions as
+	// equal to each other (diff -I).
+	IgnoreMatching []*regexp.Regexp
+	// IgnoreCase ignores case differences (diff -i).
+	IgnoreCase bool
+}
+
+// ignoredLine is the key of every line matched by IgnoreMatching. It
+// contains a byte that never appears in a text line so it matches nothing
+// else.
+const ignoredLine = "\x01ignored"
+
+func (o Options) differ() Differ {
+	if o.Algorithm == nil {
+		return Myers{}
+	}
+	return o.Algorithm
+}
+
+// normalize returns the form of line the comparison looks at.
+func (o Options) normalize(line string) string {
+	for _, re := range o.IgnoreMatching {
+		if re.MatchString(line) {
+			return ignoredLine
+		}
+	}
+	if o.StripTrailingCR {
+		line = strings.TrimSuffix(line, "\r")
+	}
+	switch {
+	case o.IgnoreAllSpace:
+		line = strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, line)
+	case o.IgnoreSpaceChange:
+		// Every run of white space counts as a single space, except at the
+		// end of the line where it does not count at all.
+		var b strings.Builder
+		inSpace := false
+		for _, r := range strings.TrimRightFunc(line, unicode.IsSpace) {
+			if unicode.IsSpace(r) {
+				inSpace = true
+				continue
+			}
+			if inSpace {
+				b.WriteByte(' ')
+				inSpace = false
+			}
+			b.WriteRune(r)
+		}
+		line = b.String()
+	case o.IgnoreTrailingSpace:
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+	if o.IgnoreCase {
+		line = strings.ToLower(line)
+	}
+	return line
+}
+
+// HeuristicMaxCost is a MaxCost that keeps diffs of large, heavily changed
+// inputs fast while leaving typical edits minimal.
+const HeuristicMaxCost = 256
+
+// diff runs the configured algorithm on a and b. Built-in algorithms stop
+// early when ctx is done; others run to completion first.
+func (o Options) diff(ctx context.Context, a, b []string) ([]Diff, error) {
+	differ := o.differ()
+	limited, ok := differ.(limitedDiffer)
+	if !ok {
+		diffs := differ.Diff(a, b)
+		return diffs, ctx.Err()
+	}
+	s := newSearch(ctx, o.MaxCost)
+	diffs := limited.diffWithin(s, a, b)
+	return diffs, s.err
+}
+
+// compare diffs two texts. Lines are matched on their normalized form but
+// keep their original text, the old side's for unchanged lines. A last
+// line without a newline never matches the same line with one.
+func (o Options) compare(ctx context.Context, text1, text2 text) ([]Diff, error) {
+	diffs, err := o.diff(ctx, text1.keys(o), text2.keys(o))
+	if err != nil {
+		return nil, err
+	}
+	for i := range diffs {
+		d := &diffs[i]
+		if d.OldLine > 0 {
+			d.Line = text1.lines[d.OldLine-1]
+			d.NoNewline = text1.missingNewline && d.OldLine == len(text1.lines)
+		} else {
+			d.Line = text2.lines[d.NewLine-1]
+			d.NoNewline = text2.missingNewline && d.NewLine == len(text2.lines)
+		}
+	}
+	if o.IgnoreBlankLines {
+		kept := make([]Diff, 0, len(diffs))
+		b := &blankHunks{context: o.Context, emit: func(d Diff) error {
+			kept = append(kept, d)
+			return nil
+		}}
+		for _, d := range diffs {
+			b.add(d)
+		}
+		b.flush()
+		diffs = kept
+	}
+	return diffs, nil
+}
+
+// blankHunks passes diffs on to emit, leaving out the changes of hunks,
+// grouped with context lines as Hunks does, that only add or remove blank
+// lines. It holds back each hunk until the next change is too far away to
+// join it, or until flush.
+type blankHunks struct {
+	context int
+	emit    func(Diff) error
+	// held runs from the first change of the open hunk.
+	held  []Diff
+	blank bool
+	// trailing counts the unchanged lines at the end of held.
+	trailing int
+}
+
+func (b *blankHunks) add(d Diff) error {
+	if len(b.held) == 0 {
+		if d.Type == "same" {
+			return b.emit(d)
+		}
+		b.blank = true
+	}
+	b.held = append(b.held, d)
+	if d.Type != "same" {
+		b.blank = b.blank && strings.TrimSpace(d.Line) == ""
+		b.trailing = 0
+		return nil
+	}
+	b.trailing++
+	if b.context >= 0 && b.trailing > 2*b.context {
+		return b.flush()
+	}
+	return nil
+}
+
+// flush passes on the open hunk.
+func (b *blankHunks) flush() error {
+	held := b.held
+	b.held, b.trailing = nil, 0
+	for _, d := range held {
+		if b.blank && d.Type != "same" {
+			continue
+		}
+		if err := b.emit(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasChanges reports whether diffs contain anything but unchanged lines.
+func HasChanges(diffs []Diff) bool {
+	for _, d := range diffs {
+		if d.Type != "same" {
+			return true
+		}
+	}
+	return false
+}
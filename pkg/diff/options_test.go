@@ -0,0 +1,234 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestFilesWithOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		input1 string
+		input2 string
+		want   []Diff
+	}{
+		{
+			name:   "ignore all space",
+			opts:   Options{IgnoreAllSpace: true},
+			input1: "if (a) {\n",
+			input2: "if(a){\n",
+			want: []Diff{
+				{Line: "if (a) {", Type: "same", OldLine: 1, NewLine: 1},
+			},
+		},
+		{
+			name:   "ignore space change keeps space significant",
+			opts:   Options{IgnoreSpaceChange: true},
+			input1: "a  b \nab\n",
+			input2: "a\tb\na b\n",
+			want: []Diff{
+				{Line: "a  b ", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "ab", Type: "remove", OldLine: 2},
+				{Line: "a b", Type: "add", NewLine: 2},
+			},
+		},
+		{
+			name:   "ignore trailing space",
+			opts:   Options{IgnoreTrailingSpace: true},
+			input1: "a  \n b\n",
+			input2: "a\nb\n",
+			want: []Diff{
+				{Line: "a  ", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: " b", Type: "remove", OldLine: 2},
+				{Line: "b", Type: "add", NewLine: 2},
+			},
+		},
+		{
+			name:   "ignore blank lines",
+			opts:   Options{IgnoreBlankLines: true},
+			input1: "a\n\nb\nc\n",
+			input2: "a\nb\n\n\nx\n",
+			want: []Diff{
+				{Line: "a", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "b", Type: "same", OldLine: 3, NewLine: 2},
+				{Line: "c", Type: "remove", OldLine: 4},
+				{Line: "", Type: "add", NewLine: 3},
+				{Line: "", Type: "add", NewLine: 4},
+				{Line: "x", Type: "add", NewLine: 5},
+			},
+		},
+		{
+			name:   "strip trailing CR",
+			opts:   Options{StripTrailingCR: true},
+			input1: "a\r\nb\r\n",
+			input2: "a\nb\n",
+			want: []Diff{
+				{Line: "a\r", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "b\r", Type: "same", OldLine: 2, NewLine: 2},
+			},
+		},
+		{
+			name:   "ignore matching lines",
+			opts:   Options{IgnoreMatching: []*regexp.Regexp{regexp.MustCompile(`^// Generated`), regexp.MustCompile(`Id:`)}},
+			input1: "// Generated 2024-01-01\nx\n$Id: 1$\n",
+			input2: "// Generated 2025-06-30\nx\n$Id: 2$\n",
+			want: []Diff{
+				{Line: "// Generated 2024-01-01", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "x", Type: "same", OldLine: 2, NewLine: 2},
+				{Line: "$Id: 1$", Type: "same", OldLine: 3, NewLine: 3},
+			},
+		},
+		{
+			name:   "ignored lines do not match other lines",
+			opts:   Options{IgnoreMatching: []*regexp.Regexp{regexp.MustCompile(`^#`)}},
+			input1: "# a\n",
+			input2: "b\n",
+			want: []Diff{
+				{Line: "# a", Type: "remove", OldLine: 1},
+				{Line: "b", Type: "add", NewLine: 1},
+			},
+		},
+		{
+			name:   "ignore case",
+			opts:   Options{IgnoreCase: true, IgnoreAllSpace: true},
+			input1: "Hello World\nfoo\n",
+			input2: "HELLOWORLD\nbar\n",
+			want: []Diff{
+				{Line: "Hello World", Type: "same", OldLine: 1, NewLine: 1},
+				{Line: "foo", Type: "remove", OldLine: 2},
+				{Line: "bar", Type: "add", NewLine: 2},
+			},
+		},
+		{
+			name:   "CR is significant by default",
+			opts:   Options{},
+			input1: "a\r\n",
+			input2: "a\n",
+			want: []Diff{
+				{Line: "a\r", Type: "remove", OldLine: 1},
+				{Line: "a", Type: "add", NewLine: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Files(strings.NewReader(tt.input1), strings.NewReader(tt.input2), tt.opts)
+			if err != nil {
+				t.Fatalf("Files() error = %v", err)
+			}
+			if !diffsEqual(got, tt.want) {
+				t.Errorf("Files() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDirectoryDiffsWithOptions(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	write := func(dir, name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(dir1, "same.txt", "a\nb\n")
+	write(dir2, "same.txt", "a\nb\n")
+	write(dir1, "space.txt", "a b\n")
+	write(dir2, "space.txt", "a   b\n")
+	write(dir1, "case.txt", "Version\n")
+	write(dir2, "case.txt", "VERSION\n")
+
+	types := func(opts Options) map[string]string {
+		diffs, err := DirectoryDiffs(dir1, dir2, opts)
+		if err != nil {
+			t.Fatalf("DirectoryDiffs() error = %v", err)
+		}
+		got := make(map[string]string)
+		for _, d := range diffs {
+			got[d.File1] = d.Type
+		}
+		return got
+	}
+
+	if got := types(Options{}); got["same.txt"] != "same" || got["space.txt"] != "change" {
+		t.Errorf("DirectoryDiffs() types = %v, want same.txt same and space.txt change", got)
+	}
+	if got := types(Options{IgnoreSpaceChange: true}); got["space.txt"] != "same" {
+		t.Errorf("DirectoryDiffs() with IgnoreSpaceChange types = %v, want space.txt same", got)
+	}
+	if got := types(Options{IgnoreCase: true}); got["case.txt"] != "same" {
+		t.Errorf("DirectoryDiffs() with IgnoreCase types = %v, want case.txt same", got)
+	}
+}
+
+func TestIgnoreBlankLinesApplies(t *testing.T) {
+	blankless := func(s string) string {
+		var lines []string
+		for _, line := range strings.Split(s, "\n") {
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	tests := []struct {
+		name    string
+		input1  string
+		input2  string
+		context int
+		want    string
+	}{
+		{name: "blank removal next to a change", input1: "a\n\nb\nc\n", input2: "a\nb\nX\n", context: 3, want: "a\nb\nX\n"},
+		{name: "blank removal within context", input1: "a\n\nb\nc\nd\n", input2: "a\nb\nc\nX\n", context: 1, want: "a\nb\nc\nX\n"},
+		{name: "blank-only hunk dropped", input1: "a\n\nb\nc\nd\ne\nf\n", input2: "a\nb\nc\nd\ne\nX\n", context: 1, want: "a\n\nb\nc\nd\ne\nX\n"},
+		{name: "whole file in one hunk", input1: "\na\nb\n", input2: "a\nX\n\n", context: -1, want: "a\nX\n\n"},
+		{name: "blank lines only", input1: "a\n\nb\n", input2: "a\nb\n\n", context: 3, want: "a\n\nb\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{IgnoreBlankLines: true, Context: tt.context}
+			diffs, err := Files(strings.NewReader(tt.input1), strings.NewReader(tt.input2), opts)
+			if err != nil {
+				t.Fatalf("Files() error = %v", err)
+			}
+			var streamed []Diff
+			err = Stream(strings.NewReader(tt.input1), strings.NewReader(tt.input2), opts, func(d Diff) error {
+				streamed = append(streamed, d)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Stream() error = %v", err)
+			}
+			if !diffsEqual(streamed, diffs) {
+				t.Errorf("Stream() = %v, want %v", streamed, diffs)
+			}
+
+			var patch strings.Builder
+			if err := WritePatch(&patch, FilePatch{OldName: "a/file", NewName: "b/file", Hunks: Hunks(diffs, tt.context)}); err != nil {
+				t.Fatalf("WritePatch() error = %v", err)
+			}
+			patches, err := ParsePatch(strings.NewReader(patch.String()))
+			if err != nil {
+				t.Fatalf("ParsePatch(%q) error = %v", patch.String(), err)
+			}
+			var hunks []Hunk
+			for _, p := range patches {
+				hunks = append(hunks, p.Hunks...)
+			}
+			result, err := Apply(strings.NewReader(tt.input1), hunks, 0)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if len(result.Rejected) > 0 {
+				t.Fatalf("Apply(%q) rejected %d hunks", patch.String(), len(result.Rejected))
+			}
+			if got := string(result.Content); got != tt.want || blankless(got) != blankless(tt.input2) {
+				t.Errorf("Apply(%q) = %q, want %q", patch.String(), got, tt.want)
+			}
+		})
+	}
+}
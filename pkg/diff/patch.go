@@ -0,0 +1,317 @@
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FilePatch holds the hunks a unified diff applies to one file. Names are
+// taken verbatim from the "---" and "+++" lines, without timestamps;
+// /dev/null marks a created or deleted file.
+type FilePatch struct {
+	OldName string
+	NewName string
+	Hunks   []Hunk
+}
+
+// DevNull is the file name unified diffs use for a missing side.
+const DevNull = "/dev/null"
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatch reads unified diff text. Lines outside of file headers and
+// hunks, such as "diff --git" or "Only in" lines, are ignored.
+func ParsePatch(r io.Reader) ([]FilePatch, error) {
+	var (
+		patches          []FilePatch
+		hunk             *Hunk
+		oldLeft, newLeft int
+		oldLine, newLine int
+		lineNo           int
+	)
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line == "" && err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			break
+		}
+		lineNo++
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case strings.HasPrefix(line, `\`) && hunk != nil && len(hunk.Lines) > 0:
+			hunk.Lines[len(hunk.Lines)-1].NoNewline = true
+		case hunk != nil && (oldLeft > 0 || newLeft > 0):
+			d := Diff{}
+			// Some tools strip the single space off empty context lines.
+			if line == "" {
+				line = " "
+			}
+			switch line[0] {
+			case ' ':
+				d = Diff{Line: line[1:], Type: "same", OldLine: oldLine, NewLine: newLine}
+				oldLeft--
+				newLeft--
+				oldLine++
+				newLine++
+			case '-':
+				d = Diff{Line: line[1:], Type: "remove", OldLine: oldLine}
+				oldLeft--
+				oldLine++
+			case '+':
+				d = Diff{Line: line[1:], Type: "add", NewLine: newLine}
+				newLeft--
+				newLine++
+			default:
+				return nil, fmt.Errorf("line %d: unexpected line in hunk: %q", lineNo, line)
+			}
+			if oldLeft < 0 || newLeft < 0 {
+				return nil, fmt.Errorf("line %d: hunk longer than its header says", lineNo)
+			}
+			hunk.Lines = append(hunk.Lines, d)
+		case strings.HasPrefix(line, "--- "):
+			hunk = nil
+			patches = append(patches, FilePatch{OldName: patchName(line[4:])})
+		case strings.HasPrefix(line, "+++ ") && len(patches) > 0 && patches[len(patches)-1].NewName == "":
+			patches[len(patches)-1].NewName = patchName(line[4:])
+		case strings.HasPrefix(line, "@@ "):
+			if len(patches) == 0 {
+				return nil, fmt.Errorf("line %d: hunk without file header", lineNo)
+			}
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: malformed hunk header: %q", lineNo, line)
+			}
+			h := Hunk{
+				OldStart: atoi(m[1]),
+				OldLines: atoiDefault(m[2], 1),
+				NewStart: atoi(m[3]),
+				NewLines: atoiDefault(m[4], 1),
+			}
+			p := &patches[len(patches)-1]
+			p.Hunks = append(p.Hunks, h)
+			hunk = &p.Hunks[len(p.Hunks)-1]
+			oldLeft, newLeft = h.OldLines, h.NewLines
+			oldLine, newLine = h.oldFirst(), h.newFirst()
+		default:
+			hunk = nil
+		}
+	}
+	if oldLeft > 0 || newLeft > 0 {
+		return nil, fmt.Errorf("unexpected end of patch inside a hunk")
+	}
+	return patches, nil
+}
+
+// patchName strips the timestamp that follows a tab in "---"/"+++" lines.
+func patchName(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	return atoi(s)
+}
+
+// WritePatch writes p in unified format. It is the inverse of ParsePatch.
+func WritePatch(w io.Writer, p FilePatch) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n", p.OldName)
+	fmt.Fprintf(&b, "+++ %s\n", p.NewName)
+	for _, h := range p.Hunks {
+		WriteHunk(&b, h)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteHunk writes h in unified format, without file headers. Changed lines
+// are written as a removal and an addition, after the other changed lines
+// next to them have been removed.
+func WriteHunk(w io.Writer, h Hunk) error {
+	var b strings.Builder
+	b.WriteString(h.Header() + "\n")
+	for _, d := range removalsFirst(plainDiffs(h.Lines)) {
+		switch d.Type {
+		case "add":
+			b.WriteString("+")
+		case "remove":
+			b.WriteString("-")
+		default:
+			b.WriteString(" ")
+		}
+		b.WriteString(d.Line + "\n")
+		if d.NoNewline {
+			b.WriteString("\\ No newline at end of file\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// HunkResult reports how one hunk was applied. Line is the 1-based line of
+// the original file where the hunk matched, or where it was expected when
+// it was rejected. Offset is the distance from the position recorded in
+// the hunk header and Fuzz the number of context lines ignored at each end.
+type HunkResult struct {
+	Applied bool
+	Line    int
+	Offset  int
+	Fuzz    int
+}
+
+// ApplyResult is the outcome of applying a file's hunks.
+type ApplyResult struct {
+	Content  []byte
+	Hunks    []HunkResult
+	Rejected []Hunk
+}
+
+// Apply applies hunks, in order, to the content read from r. Each hunk is
+// looked for at the line its header names, adjusted by the offset of the
+// previous hunk, and then progressively further away. When it cannot be
+// found as is, up to fuzz context lines are ignored at either end. Hunks
+// that still do not match are rejected and leave the content untouched.
+func Apply(r io.Reader, hunks []Hunk, fuzz int) (ApplyResult, error) {
+	t, err := readText(r)
+	if err != nil {
+		return ApplyResult{}, err
+	}
+	lines := t.keys(Options{})
+
+	type placement struct {
+		pos, oldLen int
+		newKeys     []string
+	}
+	var (
+		result     ApplyResult
+		placements []placement
+		offset     int
+		minPos     int
+	)
+	for _, h := range hunks {
+		oldKeys, newKeys := hunkKeys(h.Lines)
+		expected := h.oldFirst() - 1
+
+		res := HunkResult{Line: expected + offset + 1}
+		for f := 0; f <= fuzz && !res.Applied; f++ {
+			lead, trail := contextToTrim(h.Lines, f)
+			if f > 0 && lead < f && trail < f {
+				break // not enough context to fuzz any further
+			}
+			oldTrim, newTrim := trimKeys(oldKeys, newKeys, lead, trail)
+			want := expected + offset + lead
+			pos, ok := findLines(lines, oldTrim, want, minPos)
+			if !ok {
+				continue
+			}
+			res = HunkResult{Applied: true, Line: pos + 1, Offset: pos - (expected + lead), Fuzz: f}
+			placements = append(placements, placement{pos: pos, oldLen: len(oldTrim), newKeys: newTrim})
+			offset = res.Offset
+			minPos = pos + len(oldTrim)
+		}
+		if !res.Applied {
+			result.Rejected = append(result.Rejected, h)
+		}
+		result.Hunks = append(result.Hunks, res)
+	}
+
+	var out []string
+	prev := 0
+	for _, p := range placements {
+		out = append(out, lines[prev:p.pos]...)
+		out = append(out, p.newKeys...)
+		prev = p.pos + p.oldLen
+	}
+	out = append(out, lines[prev:]...)
+
+	var buf bytes.Buffer
+	for _, key := range out {
+		if line, ok := strings.CutSuffix(key, "\x00"); ok {
+			buf.WriteString(line)
+			continue
+		}
+		buf.WriteString(key + "\n")
+	}
+	result.Content = buf.Bytes()
+	return result, nil
+}
+
+// hunkKeys returns the old and new side of a hunk in the form text.keys
+// produces, so lines without a trailing newline only match their twin.
+func hunkKeys(lines []Diff) (oldKeys, newKeys []string) {
+	for _, d := range lines {
+		key := d.Line
+		if d.NoNewline {
+			key += "\x00"
+		}
+		if d.Type != "add" {
+			oldKeys = append(oldKeys, key)
+		}
+		if d.Type != "remove" {
+			newKeys = append(newKeys, key)
+		}
+	}
+	return oldKeys, newKeys
+}
+
+// contextToTrim returns how many leading and trailing context lines fuzz
+// level f may drop from a hunk.
+func contextToTrim(lines []Diff, f int) (lead, trail int) {
+	for lead < f && lead < len(lines) && lines[lead].Type == "same" {
+		lead++
+	}
+	for trail < f && trail < len(lines)-lead && lines[len(lines)-1-trail].Type == "same" {
+		trail++
+	}
+	return lead, trail
+}
+
+// trimKeys drops lead and trail context lines from both sides of a hunk.
+// Context lines appear on both sides, so the same count goes from each.
+func trimKeys(oldKeys, newKeys []string, lead, trail int) (oldTrim, newTrim []string) {
+	return oldKeys[lead : len(oldKeys)-trail], newKeys[lead : len(newKeys)-trail]
+}
+
+// findLines looks for want in lines starting at index at, then at
+// increasing distances on both sides, never before min.
+func findLines(lines, want []string, at, min int) (int, bool) {
+	matches := func(pos int) bool {
+		if pos < min || pos+len(want) > len(lines) {
+			return false
+		}
+		for i, line := range want {
+			if lines[pos+i] != line {
+				return false
+			}
+		}
+		return true
+	}
+	for delta := 0; at-delta >= min || at+delta <= len(lines)-len(want); delta++ {
+		if matches(at + delta) {
+			return at + delta, true
+		}
+		if delta > 0 && matches(at-delta) {
+			return at - delta, true
+		}
+	}
+	return 0, false
+}
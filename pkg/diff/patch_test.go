@@ -0,0 +1,180 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// makePatch diffs two texts into a single-file patch with three lines of
+// context.
+func makePatch(t *testing.T, input1, input2 string) FilePatch {
+	t.Helper()
+	diffs, err := Files(strings.NewReader(input1), strings.NewReader(input2), Options{})
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	return FilePatch{OldName: "a/file", NewName: "b/file", Hunks: Hunks(diffs, 3)}
+}
+
+func TestParsePatchRoundTrip(t *testing.T) {
+	p := makePatch(t, "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\nend", "1\n2\nx\n4\n5\n6\n7\n8\n9\n10\n11\ny\nend\n")
+
+	var b strings.Builder
+	if err := WritePatch(&b, p); err != nil {
+		t.Fatalf("WritePatch() error = %v", err)
+	}
+	patches, err := ParsePatch(strings.NewReader("diff -u a/file b/file\n" + b.String()))
+	if err != nil {
+		t.Fatalf("ParsePatch() error = %v", err)
+	}
+	if len(patches) != 1 {
+		t.Fatalf("ParsePatch() returned %d patches, want 1", len(patches))
+	}
+	got := patches[0]
+	if got.OldName != p.OldName || got.NewName != p.NewName {
+		t.Errorf("names = %q, %q, want %q, %q", got.OldName, got.NewName, p.OldName, p.NewName)
+	}
+	if len(got.Hunks) != len(p.Hunks) {
+		t.Fatalf("parsed %d hunks, want %d", len(got.Hunks), len(p.Hunks))
+	}
+	for i := range p.Hunks {
+		if got.Hunks[i].Header() != p.Hunks[i].Header() {
+			t.Errorf("hunk %d header = %q, want %q", i, got.Hunks[i].Header(), p.Hunks[i].Header())
+		}
+		if !diffsEqual(got.Hunks[i].Lines, p.Hunks[i].Lines) {
+			t.Errorf("hunk %d lines = %v, want %v", i, got.Hunks[i].Lines, p.Hunks[i].Lines)
+		}
+	}
+}
+
+func TestParsePatchErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{name: "hunk without header", patch: "@@ -1 +1 @@\n-a\n+b\n"},
+		{name: "malformed header", patch: "--- a\n+++ b\n@@ -x +1 @@\n"},
+		{name: "truncated hunk", patch: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n"},
+		{name: "garbage in hunk", patch: "--- a\n+++ b\n@@ -1 +1 @@\n*a\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePatch(strings.NewReader(tt.patch)); err == nil {
+				t.Errorf("ParsePatch() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	const (
+		old = "a\nb\nc\nd\ne\nf\ng\n"
+		new = "a\nb\nc\nD\ne\nf\ng\n"
+	)
+
+	tests := []struct {
+		name       string
+		patchOld   string
+		patchNew   string
+		target     string
+		fuzz       int
+		want       string
+		wantResult HunkResult
+	}{
+		{
+			name:       "exact position",
+			patchOld:   old,
+			patchNew:   new,
+			target:     old,
+			want:       new,
+			wantResult: HunkResult{Applied: true, Line: 1},
+		},
+		{
+			name:       "offset",
+			patchOld:   old,
+			patchNew:   new,
+			target:     "x\ny\n" + old,
+			want:       "x\ny\n" + new,
+			wantResult: HunkResult{Applied: true, Line: 3, Offset: 2},
+		},
+		{
+			name:       "fuzz",
+			patchOld:   old,
+			patchNew:   new,
+			target:     "A\nb\nc\nd\ne\nf\nG\n",
+			fuzz:       2,
+			want:       "A\nb\nc\nD\ne\nf\nG\n",
+			wantResult: HunkResult{Applied: true, Line: 2, Fuzz: 1},
+		},
+		{
+			name:       "rejected without fuzz",
+			patchOld:   old,
+			patchNew:   new,
+			target:     "A\nb\nc\nd\ne\nf\nG\n",
+			want:       "A\nb\nc\nd\ne\nf\nG\n",
+			wantResult: HunkResult{Line: 1},
+		},
+		{
+			name:       "missing newline",
+			patchOld:   "a\nb",
+			patchNew:   "a\nb\nc\n",
+			target:     "a\nb",
+			want:       "a\nb\nc\n",
+			wantResult: HunkResult{Applied: true, Line: 1},
+		},
+		{
+			name:       "new file",
+			patchOld:   "",
+			patchNew:   "a\nb\n",
+			target:     "",
+			want:       "a\nb\n",
+			wantResult: HunkResult{Applied: true, Line: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := makePatch(t, tt.patchOld, tt.patchNew)
+			result, err := Apply(strings.NewReader(tt.target), p.Hunks, tt.fuzz)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if got := string(result.Content); got != tt.want {
+				t.Errorf("Apply() content = %q, want %q", got, tt.want)
+			}
+			if len(result.Hunks) != 1 || result.Hunks[0] != tt.wantResult {
+				t.Errorf("Apply() hunks = %+v, want [%+v]", result.Hunks, tt.wantResult)
+			}
+			if wantRejected := !tt.wantResult.Applied; (len(result.Rejected) == 1) != wantRejected {
+				t.Errorf("Apply() rejected %d hunks, want rejected = %v", len(result.Rejected), wantRejected)
+			}
+		})
+	}
+}
+
+func TestWriteHunkPrepared(t *testing.T) {
+	rng := rand.New(rand.NewSource(10))
+	for i := 0; i < 500; i++ {
+		a, b, diffs := preparedDiffs(rng)
+		var patch strings.Builder
+		if err := WritePatch(&patch, FilePatch{OldName: "a/f", NewName: "b/f", Hunks: Hunks(diffs, 2)}); err != nil {
+			t.Fatalf("WritePatch() error = %v", err)
+		}
+		patches, err := ParsePatch(strings.NewReader(patch.String()))
+		if err != nil {
+			t.Fatalf("ParsePatch(%q) error = %v", patch.String(), err)
+		}
+		var hunks []Hunk
+		for _, p := range patches {
+			hunks = append(hunks, p.Hunks...)
+		}
+		result, err := Apply(strings.NewReader(joinLines(a)), hunks, 0)
+		if err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if got := string(result.Content); len(result.Rejected) > 0 || got != joinLines(b) {
+			t.Fatalf("Apply(%q) = %q with %d rejected hunks, want %q", patch.String(), got, len(result.Rejected), joinLines(b))
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package diff
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+	"strings"
+)
+
+// streamWindow is the number of lines of each input Stream holds at once.
+// Edit scripts are searched within a window, so memory stays bounded no
+// matter how large the inputs are.
+var streamWindow = 1024
+
+// streamHash turns a normalized line into the key Stream compares it by.
+var streamHash = func(line string) uint64 {
+	h := fnv.New64a()
+	io.WriteString(h, line)
+	return h.Sum64()
+}
+
+// Stream diffs file1 and file2 without reading them into memory, calling
+// emit for every Diff in order as soon as it is final. Lines may be of any
+// length. Only a window of lines of each input is compared at a time:
+// changes that fit in half a window come out as from Files, larger ones
+// are still correct but may not be minimal. With IgnoreBlankLines, the
+// changes of a hunk are held until it is complete. An error returned by emit
+// stops the diff and is returned.
+func Stream(file1, file2 io.Reader, opts Options, emit func(Diff) error) error {
+	return StreamContext(context.Background(), file1, file2, opts, emit)
+}
+
+// StreamContext is Stream that gives up with ctx.Err() once ctx is done.
+// The Diffs emitted until then stand, so there is no going back to an
+// approximate diff as with FilesContext.
+func StreamContext(ctx context.Context, file1, file2 io.Reader, opts Options, emit func(Diff) error) error {
+	flush := func() error { return nil }
+	if opts.IgnoreBlankLines {
+		b := &blankHunks{context: opts.Context, emit: emit}
+		emit, flush = b.add, b.flush
+	}
+	a := &streamInput{r: bufio.NewReader(file1), opts: opts, first: 1}
+	b := &streamInput{r: bufio.NewReader(file2), opts: opts, first: 1}
+	for {
+		if err := a.fill(); err != nil {
+			return err
+		}
+		if err := b.fill(); err != nil {
+			return err
+		}
+		if len(a.lines) == 0 && len(b.lines) == 0 {
+			return flush()
+		}
+
+		// Unchanged lines need no search.
+		for len(a.lines) > 0 && len(b.lines) > 0 && a.keys[0] == b.keys[0] {
+			if collision(a, b, 0, 0) {
+				useExactKeys(a, b)
+				break
+			}
+			d := Diff{Line: a.lines[0], Type: "same", OldLine: a.first, NewLine: b.first, NoNewline: a.lastMissingNewline(0)}
+			a.drop(1)
+			b.drop(1)
+			if err := emit(d); err != nil {
+				return err
+			}
+		}
+		if (len(a.lines) < streamWindow && !a.eof) || (len(b.lines) < streamWindow && !b.eof) {
+			continue
+		}
+
+		diffs, err := opts.diff(ctx, a.keys, b.keys)
+		if err != nil {
+			return err
+		}
+		if collisions(diffs, a, b) {
+			useExactKeys(a, b)
+			continue
+		}
+		oldEnd, newEnd, synced := len(a.lines), len(b.lines), true
+		if !a.eof || !b.eof {
+			oldEnd, newEnd, synced = streamCut(diffs, a, b)
+		}
+		if !synced {
+			diffs = nil
+			for i := 0; i < oldEnd; i++ {
+				diffs = append(diffs, Diff{Type: "remove", OldLine: i + 1})
+			}
+			for i := 0; i < newEnd; i++ {
+				diffs = append(diffs, Diff{Type: "add", NewLine: i + 1})
+			}
+		}
+		var chunk []Diff
+		for _, d := range diffs {
+			if (d.OldLine > 0 && d.OldLine > oldEnd) || (d.NewLine > 0 && d.NewLine > newEnd) {
+				continue
+			}
+			if d.OldLine > 0 {
+				d.Line, d.NoNewline = a.lines[d.OldLine-1], a.lastMissingNewline(d.OldLine-1)
+				d.OldLine += a.first - 1
+			} else {
+				d.Line, d.NoNewline = b.lines[d.NewLine-1], b.lastMissingNewline(d.NewLine-1)
+			}
+			if d.NewLine > 0 {
+				d.NewLine += b.first - 1
+			}
+			chunk = append(chunk, d)
+		}
+		a.drop(oldEnd)
+		b.drop(newEnd)
+		for _, d := range chunk {
+			if err := emit(d); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// streamCut decides how much of the edit script of two windows is final:
+// everything up to the last unchanged line in the first half of both. The
+// rest is searched again with more lines read. Without such a line synced
+// is false and the first halves are given up on as changed. An input that
+// has been read to the end counts as one window, so all of it is usable.
+func streamCut(diffs []Diff, a, b *streamInput) (oldEnd, newEnd int, synced bool) {
+	oldLimit, newLimit := a.limit(), b.limit()
+	for _, d := range diffs {
+		if d.Type != "same" || d.OldLine > oldLimit || d.NewLine > newLimit {
+			continue
+		}
+		oldEnd, newEnd, synced = d.OldLine, d.NewLine, true
+	}
+	if !synced {
+		return oldLimit, newLimit, false
+	}
+	return oldEnd, newEnd, true
+}
+
+// streamInput is the window of lines Stream holds of one input. first is
+// the line number of lines[0].
+type streamInput struct {
+	r     *bufio.Reader
+	opts  Options
+	lines []string
+	keys  []string
+	first int
+	eof   bool
+	// exact is set once keys hold whole lines rather than their hashes.
+	exact bool
+	// missingNewline is set once the last line was read without a trailing
+	// newline.
+	missingNewline bool
+}
+
+// fill reads lines until the window is full or the input ends.
+func (in *streamInput) fill() error {
+	for len(in.lines) < streamWindow && !in.eof {
+		line, err := in.r.ReadString('\n')
+		if err == io.EOF {
+			in.eof = true
+		} else if err != nil {
+			return err
+		}
+		if line == "" {
+			break
+		}
+		if strings.HasSuffix(line, "\n") {
+			line = line[:len(line)-1]
+		} else {
+			in.missingNewline = true
+		}
+		in.lines = append(in.lines, line)
+		in.keys = append(in.keys, in.key(line, in.missingNewline))
+	}
+	return nil
+}
+
+// drop discards the first n lines of the window.
+func (in *streamInput) drop(n int) {
+	in.lines = in.lines[n:]
+	in.keys = in.keys[n:]
+	in.first += n
+}
+
+// limit is the number of lines of the window whose edit script Stream may
+// settle on.
+func (in *streamInput) limit() int {
+	if in.eof {
+		return len(in.lines)
+	}
+	return len(in.lines) / 2
+}
+
+// lastMissingNewline reports whether lines[i] is the last line of the input
+// and has no trailing newline.
+func (in *streamInput) lastMissingNewline(i int) bool {
+	return in.missingNewline && i == len(in.lines)-1
+}
+
+// text is the normalized form of a line that its key stands for.
+func (in *streamInput) text(line string, missingNewline bool) string {
+	line = in.opts.normalize(line)
+	if missingNewline {
+		line += "\x00"
+	}
+	return line
+}
+
+// key is the key of a line: a hash of its text, so that keys take the same
+// small amount of memory however long the line is, or the text itself once
+// hashes are known to collide.
+func (in *streamInput) key(line string, missingNewline bool) string {
+	text := in.text(line, missingNewline)
+	if in.exact {
+		return text
+	}
+	return string(binary.BigEndian.AppendUint64(nil, streamHash(text)))
+}
+
+// collision reports whether line i of a and line j of b, whose keys are
+// equal, differ after all.
+func collision(a, b *streamInput, i, j int) bool {
+	return !a.exact && a.text(a.lines[i], a.lastMissingNewline(i)) != b.text(b.lines[j], b.lastMissingNewline(j))
+}
+
+// collisions reports whether diffs, the edit script of the windows of a and
+// b, matches lines that differ.
+func collisions(diffs []Diff, a, b *streamInput) bool {
+	if a.exact {
+		return false
+	}
+	for _, d := range diffs {
+		if d.Type == "same" && collision(a, b, d.OldLine-1, d.NewLine-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// useExactKeys switches a and b to keys holding whole lines, which only
+// match lines that are equal.
+func useExactKeys(a, b *streamInput) {
+	for _, in := range []*streamInput{a, b} {
+		in.exact = true
+		for i, line := range in.lines {
+			in.keys[i] = in.key(line, in.lastMissingNewline(i))
+		}
+	}
+}
+
+// StreamHunks is Stream grouped into hunks the way Hunks groups them, with
+// emit called for each hunk once its trailing context has been read. Only
+// the hunk being built is held in memory. A negative contextLines keeps
+// every line in a single hunk and so holds the whole diff. contextLines
+// overrides opts.Context.
+func StreamHunks(file1, file2 io.Reader, opts Options, contextLines int, emit func(Hunk) error) error {
+	return StreamHunksContext(context.Background(), file1, file2, opts, contextLines, emit)
+}
+
+// StreamHunksContext is StreamHunks that gives up with ctx.Err() once ctx
+// is done.
+func StreamHunksContext(ctx context.Context, file1, file2 io.Reader, opts Options, contextLines int, emit func(Hunk) error) error {
+	opts.Context = contextLines
+	var (
+		// lines is the open hunk or, between hunks, the unchanged lines
+		// that may become the leading context of the next one.
+		lines []Diff
+		open  bool
+		// trailing counts the unchanged lines at the end of an open hunk.
+		trailing int
+		// oldBefore and newBefore are the line numbers preceding lines[0].
+		oldBefore, newBefore int
+	)
+	skip := func(n int) {
+		for _, d := range lines[:n] {
+			if d.inOld() {
+				oldBefore = d.OldLine
+			}
+			if d.inNew() {
+				newBefore = d.NewLine
+			}
+		}
+		lines = lines[n:]
+	}
+	closeHunk := func() error {
+		keep := trailing
+		if contextLines >= 0 {
+			keep = min(trailing, contextLines)
+		}
+		n := len(lines) - trailing + keep
+		h := streamHunk(lines[:n], oldBefore, newBefore)
+		skip(n)
+		if contextLines >= 0 && len(lines) > contextLines {
+			skip(len(lines) - contextLines)
+		}
+		open, trailing = false, 0
+		return emit(h)
+	}
+
+	err := StreamContext(ctx, file1, file2, opts, func(d Diff) error {
+		lines = append(lines, d)
+		switch {
+		case d.Type != "same":
+			open, trailing = true, 0
+		case open:
+			trailing++
+			if contextLines >= 0 && trailing > 2*contextLines {
+				return closeHunk()
+			}
+		case contextLines >= 0 && len(lines) > contextLines:
+			skip(1)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if open {
+		return closeHunk()
+	}
+	return nil
+}
+
+// streamHunk makes a hunk of lines, given the line numbers just before it.
+func streamHunk(lines []Diff, oldBefore, newBefore int) Hunk {
+	h := Hunk{OldStart: oldBefore, NewStart: newBefore, Lines: lines}
+	for _, d := range lines {
+		if d.inOld() {
+			if h.OldLines == 0 {
+				h.OldStart = d.OldLine
+			}
+			h.OldLines++
+		}
+		if d.inNew() {
+			if h.NewLines == 0 {
+				h.NewStart = d.NewLine
+			}
+			h.NewLines++
+		}
+	}
+	return h
+}
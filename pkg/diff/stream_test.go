@@ -0,0 +1,205 @@
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// streamAll collects the output of Stream.
+func streamAll(t *testing.T, input1, input2 string, opts Options) []Diff {
+	t.Helper()
+	var diffs []Diff
+	err := Stream(strings.NewReader(input1), strings.NewReader(input2), opts, func(d Diff) error {
+		diffs = append(diffs, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	return diffs
+}
+
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestStreamMatchesFiles(t *testing.T) {
+	inputs := [][2]string{
+		{"", ""},
+		{"a\nb\nc\n", "a\nb\nc\n"},
+		{"a\nb\nc", "a\nb\nc\n"},
+		{"a\nb\n", "a\nx\nb\ny"},
+		{"", "a\n"},
+	}
+	rng := rand.New(rand.NewSource(7))
+	for i := 0; i < 200; i++ {
+		a := randomText(rng)
+		inputs = append(inputs, [2]string{joinLines(a), joinLines(mutate(rng, a))})
+	}
+
+	for _, opts := range []Options{{}, {IgnoreAllSpace: true}, {IgnoreBlankLines: true}} {
+		for _, in := range inputs {
+			want, err := Files(strings.NewReader(in[0]), strings.NewReader(in[1]), opts)
+			if err != nil {
+				t.Fatalf("Files() error = %v", err)
+			}
+			if got := streamAll(t, in[0], in[1], opts); !diffsEqual(got, want) {
+				t.Errorf("Stream(%q, %q) = %v, want %v", in[0], in[1], got, want)
+			}
+		}
+	}
+}
+
+func TestStreamWindowed(t *testing.T) {
+	defer func(n int) { streamWindow = n }(streamWindow)
+	streamWindow = 8
+
+	rng := rand.New(rand.NewSource(11))
+	for i := 0; i < 300; i++ {
+		var a []string
+		for n := rng.Intn(5); n >= 0; n-- {
+			a = append(a, randomText(rng)...)
+		}
+		b := append([]string(nil), a...)
+		for n := rng.Intn(6); n > 0; n-- {
+			b = mutate(rng, b)
+		}
+		if rng.Intn(4) == 0 {
+			b = append(randomText(rng), b...)
+		}
+
+		got := streamAll(t, joinLines(a), joinLines(b), Options{})
+		old, new := applyDiffs(got)
+		if joinLines(old) != joinLines(a) || joinLines(new) != joinLines(b) {
+			t.Fatalf("Stream(%q, %q) = %v does not turn one into the other", a, b, got)
+		}
+		oldLine, newLine := 0, 0
+		for _, d := range got {
+			if d.Type != "add" {
+				oldLine++
+				if d.OldLine != oldLine {
+					t.Fatalf("Stream(%q, %q): %v has old line %d, want %d", a, b, d, d.OldLine, oldLine)
+				}
+			}
+			if d.Type != "remove" {
+				newLine++
+				if d.NewLine != newLine {
+					t.Fatalf("Stream(%q, %q): %v has new line %d, want %d", a, b, d, d.NewLine, newLine)
+				}
+			}
+		}
+	}
+}
+
+func TestStreamLongLines(t *testing.T) {
+	long := strings.Repeat("x", 1<<20)
+	got := streamAll(t, "a\n"+long+"\n", "a\n"+long+"y\n", Options{})
+	want := []Diff{
+		{Line: "a", Type: "same", OldLine: 1, NewLine: 1},
+		{Line: long, Type: "remove", OldLine: 2},
+		{Line: long + "y", Type: "add", NewLine: 2},
+	}
+	if !diffsEqual(got, want) {
+		t.Errorf("Stream() with a 1MB line returned %d diffs, want the long line changed", len(got))
+	}
+}
+
+func TestStreamEmitError(t *testing.T) {
+	stop := fmt.Errorf("stop")
+	calls := 0
+	err := Stream(strings.NewReader("a\nb\n"), strings.NewReader("a\nc\n"), Options{}, func(Diff) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("Stream() = %v after %d calls, want %v after 1", err, calls, stop)
+	}
+}
+
+func TestStreamHunks(t *testing.T) {
+	defer func(n int) { streamWindow = n }(streamWindow)
+	streamWindow = 64
+
+	rng := rand.New(rand.NewSource(5))
+	for i := 0; i < 300; i++ {
+		a := randomText(rng)
+		b := mutate(rng, mutate(rng, a))
+		input1, input2 := joinLines(a), joinLines(b)
+		diffs, err := Files(strings.NewReader(input1), strings.NewReader(input2), Options{})
+		if err != nil {
+			t.Fatalf("Files() error = %v", err)
+		}
+
+		for _, context := range []int{-1, 0, 1, 3} {
+			var got []Hunk
+			err := StreamHunks(strings.NewReader(input1), strings.NewReader(input2), Options{}, context, func(h Hunk) error {
+				got = append(got, h)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("StreamHunks() error = %v", err)
+			}
+			want := Hunks(diffs, context)
+			if len(got) != len(want) {
+				t.Fatalf("StreamHunks(%q, %q, %d) made %d hunks, want %d", a, b, context, len(got), len(want))
+			}
+			for j := range want {
+				if got[j].Header() != want[j].Header() || !diffsEqual(got[j].Lines, want[j].Lines) {
+					t.Fatalf("StreamHunks(%q, %q, %d) hunk %d = %s %v, want %s %v", a, b, context, j,
+						got[j].Header(), got[j].Lines, want[j].Header(), want[j].Lines)
+				}
+			}
+		}
+	}
+}
+
+func TestStreamContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	input1, input2 := unrelatedLines("a", 100), unrelatedLines("b", 100)
+	err := StreamContext(ctx, strings.NewReader(input1), strings.NewReader(input2), Options{}, func(Diff) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("StreamContext() error = %v, want %v", err, context.Canceled)
+	}
+	err = StreamHunksContext(ctx, strings.NewReader(input1), strings.NewReader(input2), Options{}, 3, func(Hunk) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("StreamHunksContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestStreamHashCollisions(t *testing.T) {
+	defer func(hash func(string) uint64) { streamHash = hash }(streamHash)
+	defer func(n int) { streamWindow = n }(streamWindow)
+	streamWindow = 8
+
+	for name, hash := range map[string]func(string) uint64{
+		"all lines collide": func(string) uint64 { return 0 },
+		"same length":       func(line string) uint64 { return uint64(len(line)) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			streamHash = hash
+			rng := rand.New(rand.NewSource(13))
+			for i := 0; i < 100; i++ {
+				a := randomText(rng)
+				input1, input2 := joinLines(a), joinLines(mutate(rng, a))
+				got := streamAll(t, input1, input2, Options{})
+				old, new := applyDiffs(got)
+				if joinLines(old) != input1 || joinLines(new) != input2 {
+					t.Fatalf("Stream(%q, %q) = %v does not turn one into the other", input1, input2, got)
+				}
+			}
+
+			differ, err := FilesDiffer(strings.NewReader("a\nb\n"), strings.NewReader("a\nc\n"), Options{})
+			if err != nil || !differ {
+				t.Errorf("FilesDiffer() = %v, %v, want true", differ, err)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package diff
 
 import (
+	"context"
 	"fmt"
 	"go/ast"
 	"go/parser"
@@ -18,6 +19,12 @@ type StructuralDiff struct {
 }
 
 func StructuralDiffs(file1, file2 io.Reader) ([]StructuralDiff, error) {
+	return StructuralDiffsContext(context.Background(), file1, file2)
+}
+
+// StructuralDiffsContext is StructuralDiffs that gives up with ctx.Err()
+// once ctx is done. A file being parsed is finished first.
+func StructuralDiffsContext(ctx context.Context, file1, file2 io.Reader) ([]StructuralDiff, error) {
 	fset := token.NewFileSet()
 
 	// Parse file1
@@ -26,12 +33,20 @@ func StructuralDiffs(file1, file2 io.Reader) ([]StructuralDiff, error) {
 		return nil, fmt.Errorf("parsing file1: %w", err)
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Parse file2
 	f2, err := parser.ParseFile(fset, "file2.go", file2, parser.ParseComments)
 	if err != nil {
 		return nil, fmt.Errorf("parsing file2: %w", err)
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	funcs1 := extractFuncs(f1)
 	funcs2 := extractFuncs(f2)
 
@@ -14,23 +14,18 @@ func Heatmap(diffs []diff.Diff, file1Lines, file2Lines []string, w io.Writer) {
 
 	heat := make([]int, maxLength)
 
+	// Entries without a line number in range, as diffs from a custom
+	// Differ or a parsed patch may have, add no heat.
 	for _, d := range diffs {
+		line := 0
 		switch d.Type {
 		case "add":
-			for i, line := range file2Lines {
-				if line == d.Line {
-					heat[i]++
-					break
-				}
-			}
-
+			line = d.NewLine
 		case "remove":
-			for i, line := range file1Lines {
-				if line == d.Line {
-					heat[i]++
-					break
-				}
-			}
+			line = d.OldLine
+		}
+		if line >= 1 && line <= len(heat) {
+			heat[line-1]++
 		}
 	}
 
@@ -57,7 +52,7 @@ func Heatmap(diffs []diff.Diff, file1Lines, file2Lines []string, w io.Writer) {
 			displayLine = line2
 		}
 
-		fmt.Fprintf(w, "%s%s\033[0m\n", heatColor, displayLine)
+		fmt.Fprintf(w, "%4d %s%s\033[0m\n", i+1, heatColor, displayLine)
 	}
 }
 
@@ -0,0 +1,28 @@
+package display
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+func TestHeatmapLineNumbers(t *testing.T) {
+	tests := []struct {
+		name  string
+		diffs []diff.Diff
+	}{
+		{name: "numbered", diffs: []diff.Diff{{Line: "b", Type: "remove", OldLine: 2}, {Line: "c", Type: "add", NewLine: 2}}},
+		{name: "without line numbers", diffs: []diff.Diff{{Line: "b", Type: "remove"}, {Line: "c", Type: "add"}}},
+		{name: "out of range", diffs: []diff.Diff{{Line: "b", Type: "remove", OldLine: 9}, {Line: "c", Type: "add", NewLine: -1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			Heatmap(tt.diffs, []string{"a", "b"}, []string{"a", "c"}, &b)
+			if got := strings.Count(b.String(), "\n"); got < 2 {
+				t.Errorf("Heatmap() wrote %d lines, want one per line of the longer file", got)
+			}
+		})
+	}
+}
@@ -1,163 +1,554 @@
 package display
 
 import (
+	"embed"
+	"errors"
 	"fmt"
-	"html"
+	"html/template"
 	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/san-kum/diff-dance/pkg/diff"
 )
 
-func HTML(diffs []diff.Diff, w io.Writer) error {
-	const tmpl = `<!DOCTYPE html>
-<html>
-<head>
-<meta charset="UTF-8">
-<title>diff-dance</title>
-<style>
-body { font-family: monospace; }
-.add { color: green; }
-.remove { color: red; }
-.context { color: black; }
-.highlight { background-color: yellow; font-weight: bold; }
-</style>
-</head>
-<body>
-<pre>
-%s
-</pre>
-</body>
-</html>
-  `
-	var htmlBuilder strings.Builder
+// htmlFS holds the built-in templates, styles and scripts of the HTML
+// output. file.html renders HTML and dir.html HTMLDir; both use the pieces
+// defined in layout.html.
+//
+//go:embed templates
+var htmlFS embed.FS
 
-	for _, d := range diffs {
-		var line string
+var htmlTemplates = template.Must(template.ParseFS(htmlFS, "templates/*.html"))
+
+// HTMLThemes are the built-in color schemes of the HTML output.
+var HTMLThemes = []string{"light", "dark", "high-contrast"}
+
+// HTML writes diffs as a self-contained HTML page. The changes are shown in
+// a table with the old and new lines side by side, so both sides scroll
+// together, or in a single unified column; a button or the "v" key
+// switches between the two. Unchanged lines further than opts.Context from
+// a change are collapsed behind buttons that show them, and "n" and "p"
+// jump between hunks. opts.Template and opts.Theme change how the page
+// looks.
+func HTML(diffs []diff.Diff, w io.Writer, opts Options) error {
+	return htmlReport(diffs, w, nil, opts)
+}
+
+// HTMLWithHighlight is HTML with the matches of searchRegex highlighted.
+func HTMLWithHighlight(diffs []diff.Diff, w io.Writer, searchRegex *regexp.Regexp, opts Options) error {
+	return htmlReport(diffs, w, searchRegex, opts)
+}
+
+// htmlHead is what every page passes to the head and foot templates.
+type htmlHead struct {
+	Title     string
+	BodyClass string
+	Style     template.CSS
+	Script    template.JS
+}
+
+// htmlFilePage is the data of file.html.
+type htmlFilePage struct {
+	htmlHead
+	Identical bool
+	Tables    []htmlTable
+}
+
+// htmlDirPage is the data of dir.html.
+type htmlDirPage struct {
+	htmlHead
+	Summary htmlSummary
+	Tree    []*htmlTreeNode
+	Files   []*htmlFile
+}
+
+// htmlSummary counts the files and lines of a directory report.
+type htmlSummary struct {
+	Files, Changed, Added, Removed, Unchanged int
+	AddedLines, RemovedLines                  int
+}
+
+// htmlTable is one view of a diff, "split" with the old and new lines side
+// by side or "unified" with one after the other.
+type htmlTable struct {
+	View    string
+	Columns int
+	Groups  []htmlGroup
+}
+
+// htmlGroup is a hunk or, without a Header, unchanged lines between hunks
+// that the page collapses. ID names the element holding those.
+type htmlGroup struct {
+	Header string
+	ID     string
+	Hidden int
+	Rows   []htmlRow
+}
+
+type htmlRow []htmlCell
+
+// htmlCell is a cell of a row: a line number when Class is "lineno" and
+// the text of a line otherwise.
+type htmlCell struct {
+	Class  string
+	Number int
+	Sign   string
+	Text   []htmlFragment
+	Move   *htmlMove
+}
+
+// htmlFragment is a piece of a line, marked as removed or added within a
+// changed line or as a search match.
+type htmlFragment struct {
+	Text           string
+	Del, Ins, Mark bool
+}
+
+// htmlMove links the first line of a moved block to the other end of the
+// move.
+type htmlMove struct {
+	ID, Target, Note string
+}
+
+func htmlReport(diffs []diff.Diff, w io.Writer, search *regexp.Regexp, opts Options) error {
+	head, t, err := htmlPage(opts, "diff-dance", "report.js")
+	if err != nil {
+		return err
+	}
+	diffs = opts.prepare(diffs)
+	r := htmlRenderer{search: search, links: moveLinks(diffs), opts: opts}
+	segments := htmlSegments(diffs, opts.Context)
+	return t.ExecuteTemplate(w, "file.html", htmlFilePage{
+		htmlHead:  head,
+		Identical: len(segments) == 0 || len(segments) == 1 && segments[0].hunk == nil,
+		Tables:    r.tables(segments),
+	})
+}
+
+// htmlPage loads the templates and the style of the HTML output and the
+// given scripts.
+func htmlPage(opts Options, title string, scripts ...string) (htmlHead, *template.Template, error) {
+	// Clone as a template can no longer be changed once executed.
+	t, err := htmlTemplates.Clone()
+	if err != nil {
+		return htmlHead{}, nil, err
+	}
+	if opts.Template != "" {
+		if t, err = t.ParseGlob(filepath.Join(opts.Template, "*.html")); err != nil {
+			return htmlHead{}, nil, fmt.Errorf("loading templates: %w", err)
+		}
+	}
+	style, err := htmlStyle(opts.Theme)
+	if err != nil {
+		return htmlHead{}, nil, err
+	}
+	var script strings.Builder
+	for _, name := range scripts {
+		content, err := htmlFS.ReadFile("templates/" + name)
+		if err != nil {
+			return htmlHead{}, nil, err
+		}
+		script.Write(content)
+	}
+	return htmlHead{Title: title, Style: style, Script: template.JS(script.String())}, t, nil
+}
+
+// htmlStyle returns the CSS of the HTML output in theme, which is one of
+// HTMLThemes or the path of a CSS file applied on top of the light theme.
+func htmlStyle(theme string) (template.CSS, error) {
+	var custom []byte
+	switch {
+	case theme == "":
+		theme = "light"
+	case !slices.Contains(HTMLThemes, theme):
+		var err error
+		if custom, err = os.ReadFile(theme); err != nil {
+			if errors.Is(err, fs.ErrNotExist) && filepath.Ext(theme) != ".css" {
+				return "", fmt.Errorf("unknown theme %q, want %s or a CSS file", theme, strings.Join(HTMLThemes, ", "))
+			}
+			return "", fmt.Errorf("reading theme: %w", err)
+		}
+		theme = "light"
+	}
+	colors, err := htmlFS.ReadFile("templates/themes/" + theme + ".css")
+	if err != nil {
+		return "", err
+	}
+	base, err := htmlFS.ReadFile("templates/style.css")
+	if err != nil {
+		return "", err
+	}
+	return template.CSS(string(colors) + string(base) + string(custom)), nil
+}
+
+// htmlSegment is a hunk or, when hunk is nil, a run of unchanged lines
+// between hunks that the page collapses.
+type htmlSegment struct {
+	hunk  *diff.Hunk
+	lines []diff.Diff
+}
+
+// htmlSegments splits diffs into hunks and the gaps between them.
+func htmlSegments(diffs []diff.Diff, context int) []htmlSegment {
+	var segments []htmlSegment
+	pos := 0
+	for _, h := range diff.Hunks(diffs, context) {
+		// Only unchanged lines lie between hunks, one per line of either
+		// side, so the line numbers tell how many come before this one.
+		start := pos
+		if first := h.Lines[0]; diffs[pos].Type == "same" {
+			if first.OldLine > 0 {
+				start += first.OldLine - diffs[pos].OldLine
+			} else {
+				start += first.NewLine - diffs[pos].NewLine
+			}
+		}
+		if start > pos {
+			segments = append(segments, htmlSegment{lines: diffs[pos:start]})
+		}
+		segments = append(segments, htmlSegment{hunk: &h, lines: h.Lines})
+		pos = start + len(h.Lines)
+	}
+	if pos < len(diffs) {
+		segments = append(segments, htmlSegment{lines: diffs[pos:]})
+	}
+	return segments
+}
+
+// htmlRenderer lays out both views of a diff. prefix keeps the element ids
+// unique when a page shows several files.
+type htmlRenderer struct {
+	prefix string
+	search *regexp.Regexp
+	links  map[int]moveLink
+	opts   Options
+}
+
+// tables returns the split and the unified view of segments.
+func (r htmlRenderer) tables(segments []htmlSegment) []htmlTable {
+	return []htmlTable{
+		r.table("split", 4, segments, r.splitRows),
+		r.table("unified", 3, segments, r.unifiedRows),
+	}
+}
+
+func (r htmlRenderer) table(view string, columns int, segments []htmlSegment, rows func(string, []diff.Diff) []htmlRow) htmlTable {
+	t := htmlTable{View: view, Columns: columns}
+	for i, s := range segments {
+		g := htmlGroup{Rows: rows(view, s.lines)}
+		if s.hunk != nil {
+			g.Header = s.hunk.Header()
+		} else {
+			g.ID = fmt.Sprintf("%s%s-gap-%d", r.prefix, view, i)
+			g.Hidden = len(s.lines)
+		}
+		t.Groups = append(t.Groups, g)
+	}
+	return t
+}
+
+// splitRows lays out lines as rows of old and new cells, paired as in the
+// terminal side-by-side view.
+func (r htmlRenderer) splitRows(view string, lines []diff.Diff) []htmlRow {
+	var rows []htmlRow
+	for _, row := range sideRows(lines) {
+		if row.old != nil && row.old.Type == "change" {
+			oldSpans, newSpans := diff.IntraLine(row.old.OldText, row.old.Line, r.opts.IntraLine)
+			rows = append(rows, htmlRow{
+				htmlLineNumber(row.old.OldLine), {Class: "remove", Text: r.text(row.old.OldText, oldSpans, true)},
+				htmlLineNumber(row.new.NewLine), {Class: "add", Text: r.text(row.new.Line, newSpans, false)},
+			})
+			continue
+		}
+		var cells htmlRow
+		if row.old != nil {
+			cells = append(cells, htmlLineNumber(row.old.OldLine), r.line(view, *row.old, ""))
+		} else {
+			cells = append(cells, htmlLineNumber(0), htmlCell{Class: "empty"})
+		}
+		if row.new != nil {
+			cells = append(cells, htmlLineNumber(row.new.NewLine), r.line(view, *row.new, ""))
+		} else {
+			cells = append(cells, htmlLineNumber(0), htmlCell{Class: "empty"})
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+// unifiedRows lays out lines one per row, a change as a removed and an
+// added row.
+func (r htmlRenderer) unifiedRows(view string, lines []diff.Diff) []htmlRow {
+	var rows []htmlRow
+	for _, d := range lines {
 		switch d.Type {
-		case "add":
-			line = fmt.Sprintf(`<span class="add">+ %s</span>`, html.EscapeString(d.Line))
-		case "remove":
-			line = fmt.Sprintf(`<span class="remove>- %s</span>`, html.EscapeString(d.Line))
-		case "same":
-			line = fmt.Sprintf(`<span class="context> %s</span>`, html.EscapeString(d.Line))
-		}
-		htmlBuilder.WriteString(line + "\n")
-	}
-	_, err := fmt.Fprintf(w, tmpl, htmlBuilder.String())
-	return err
-}
-
-func HTMLWithHighlight(diffs []diff.Diff, w io.Writer, searchRegex *regexp.Regexp) error {
-	const tmpl = `<!DOCTYPE html>
-<html>
-<head>
-<meta charset="UTF-8">
-<title>diff-dance</title>
-<style>
-body { font-family: monospace; }
-.add { color: green; }
-.remove { color: red; }
-.context { color: black; }
-.highlight { background-color: yellow; font-weight: bold; }
-</style>
-</head>
-<body>
-<pre>
-%s
-</pre>
-</body>
-</html>`
-
-	var htmlBuilder strings.Builder
+		case "change":
+			oldSpans, newSpans := diff.IntraLine(d.OldText, d.Line, r.opts.IntraLine)
+			rows = append(rows,
+				htmlRow{htmlLineNumber(d.OldLine), htmlLineNumber(0), {Class: "remove", Sign: "-", Text: r.text(d.OldText, oldSpans, true)}},
+				htmlRow{htmlLineNumber(0), htmlLineNumber(d.NewLine), {Class: "add", Sign: "+", Text: r.text(d.Line, newSpans, false)}})
+		case "remove", "move_from":
+			rows = append(rows, htmlRow{htmlLineNumber(d.OldLine), htmlLineNumber(0), r.line(view, d, "-")})
+		case "add", "move_to":
+			rows = append(rows, htmlRow{htmlLineNumber(0), htmlLineNumber(d.NewLine), r.line(view, d, "+")})
+		default:
+			rows = append(rows, htmlRow{htmlLineNumber(d.OldLine), htmlLineNumber(d.NewLine), r.line(view, d, " ")})
+		}
+	}
+	return rows
+}
+
+// line lays out the text of d. The first line of a moved block gets an
+// anchor and links to the other end of the move; view keeps the anchors of
+// the two tables apart.
+func (r htmlRenderer) line(view string, d diff.Diff, sign string) htmlCell {
+	cell := htmlCell{Class: htmlClass(d.Type), Sign: sign, Text: r.text(d.Line, nil, false)}
+	if note := moveNote(d, r.links); note != "" {
+		here, there := "from", "to"
+		if d.Type == "move_to" {
+			here, there = "to", "from"
+		}
+		id := fmt.Sprintf("%s%s-move-%d-", r.prefix, view, d.MoveID)
+		cell.Move = &htmlMove{ID: id + here, Target: id + there, Note: note}
+	}
+	return cell
+}
+
+// text cuts s into fragments at every span and search match boundary.
+// The spans are marked as removed on the old side of a change and as added
+// on the new one.
+func (r htmlRenderer) text(s string, spans []diff.Span, old bool) []htmlFragment {
+	var matches []diff.Span
+	if r.search != nil {
+		for _, m := range r.search.FindAllStringIndex(s, -1) {
+			if m[0] < m[1] {
+				matches = append(matches, diff.Span{Start: m[0], End: m[1]})
+			}
+		}
+	}
+	bounds := []int{0, len(s)}
+	for _, sp := range spans {
+		bounds = append(bounds, sp.Start, sp.End)
+	}
+	for _, sp := range matches {
+		bounds = append(bounds, sp.Start, sp.End)
+	}
+	sort.Ints(bounds)
+
+	var fragments []htmlFragment
+	for i := 1; i < len(bounds); i++ {
+		start, end := bounds[i-1], bounds[i]
+		if start == end {
+			continue
+		}
+		changed := inSpans(spans, start)
+		fragments = append(fragments, htmlFragment{
+			Text: s[start:end],
+			Del:  changed && old,
+			Ins:  changed && !old,
+			Mark: inSpans(matches, start),
+		})
+	}
+	return fragments
+}
 
+func inSpans(spans []diff.Span, i int) bool {
+	for _, sp := range spans {
+		if i >= sp.Start && i < sp.End {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlClass is the CSS class of a line of type t.
+func htmlClass(t string) string {
+	if t == "same" {
+		return "context"
+	}
+	return t
+}
+
+func htmlLineNumber(n int) htmlCell {
+	return htmlCell{Class: "lineno", Number: n}
+}
+
+// HTMLDir writes a self-contained HTML report of a directory diff: a
+// summary of the changes, a sidebar with the tree of changed files and
+// every file's diff in a collapsible section that the tree links to. The
+// diffs are shown as HTML shows them.
+func HTMLDir(diffs []diff.DirectoryDiff, w io.Writer, opts Options) error {
+	head, t, err := htmlPage(opts, "diff-dance - Directory Diff", "report.js", "dir.js")
+	if err != nil {
+		return err
+	}
+	head.BodyClass = "dir"
+	page := htmlDirPage{htmlHead: head}
+
+	ids := make(map[string]bool)
+	tree := &htmlTreeNode{}
 	for _, d := range diffs {
-		var line string
 		switch d.Type {
+		case "same":
+			page.Summary.Unchanged++
+			continue
+		case "same_dir":
+			continue
+		}
+		path := d.File1
+		if path == "" {
+			path = d.File2
+		}
+		path = filepath.ToSlash(path)
+		node := tree.add(path)
+		if d.Type == "add_dir" || d.Type == "remove_dir" {
+			node.Status = strings.TrimSuffix(d.Type, "_dir")
+			node.dir = true
+			continue
+		}
+
+		f := &htmlFile{Path: path, Status: d.Type, ID: htmlFileID(path, ids), Binary: d.BinaryDiff}
+		if f.Status == "change" && !f.Binary {
+			fileDiffs := opts.prepare(d.Diffs)
+			f.Added, f.Removed = lineCounts(fileDiffs)
+			r := htmlRenderer{prefix: f.ID + "-", links: moveLinks(fileDiffs), opts: opts}
+			f.Tables = r.tables(htmlSegments(fileDiffs, opts.Context))
+		}
+		node.File, node.Status = f, f.Status
+
+		page.Summary.Files++
+		switch f.Status {
 		case "add":
-			line = fmt.Sprintf(`<span class="add">+ %s</span>`, html.EscapeString(d.Line))
+			page.Summary.Added++
 		case "remove":
-			line = fmt.Sprintf(`<span class="remove>- %s</span>`, html.EscapeString(d.Line))
-		case "same":
-			line = fmt.Sprintf(`<span class="context> %s</span>`, html.EscapeString(d.Line))
+			page.Summary.Removed++
+		default:
+			page.Summary.Changed++
 		}
+		page.Summary.AddedLines += f.Added
+		page.Summary.RemovedLines += f.Removed
+	}
 
-		if searchRegex != nil {
-			line = searchRegex.ReplaceAllStringFunc(line, func(match string) string {
-				return fmt.Sprintf(`<span class="highlight">%s</span>`, match)
-			})
+	// The sections follow the tree.
+	tree.sort()
+	page.Tree = tree.Children
+	page.Files = tree.files(nil)
+	return t.ExecuteTemplate(w, "dir.html", page)
+}
+
+// htmlFile is a file shown in a directory report.
+type htmlFile struct {
+	Path   string
+	Status string
+	ID     string
+	Binary bool
+	Tables []htmlTable
+
+	Added, Removed int
+}
+
+// HasStats reports whether the numbers of added and removed lines of f are
+// known, which they are for changed text files only.
+func (f *htmlFile) HasStats() bool {
+	return f.Status == "change" && !f.Binary
+}
+
+// htmlTreeNode is a file or directory in the sidebar of a directory
+// report. Directories only get a status when they were added or removed as
+// a whole.
+type htmlTreeNode struct {
+	Name     string
+	Path     string
+	Status   string
+	File     *htmlFile
+	Children []*htmlTreeNode
+
+	dir bool
+}
+
+// add returns the node of the slash-separated path, creating it and its
+// parent directories as needed.
+func (n *htmlTreeNode) add(path string) *htmlTreeNode {
+	name, rest, more := strings.Cut(path, "/")
+	var child *htmlTreeNode
+	for _, c := range n.Children {
+		if c.Name == name {
+			child = c
+			break
+		}
+	}
+	if child == nil {
+		child = &htmlTreeNode{Name: name, Path: strings.TrimPrefix(n.Path+"/"+name, "/")}
+		n.Children = append(n.Children, child)
+	}
+	if !more {
+		return child
+	}
+	child.dir = true
+	return child.add(rest)
+}
+
+// sort orders the children of n and below, directories first.
+func (n *htmlTreeNode) sort() {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		if n.Children[i].dir != n.Children[j].dir {
+			return n.Children[i].dir
+		}
+		return n.Children[i].Name < n.Children[j].Name
+	})
+	for _, c := range n.Children {
+		c.sort()
+	}
+}
+
+// files appends the files below n to files in tree order.
+func (n *htmlTreeNode) files(files []*htmlFile) []*htmlFile {
+	for _, c := range n.Children {
+		if c.File != nil {
+			files = append(files, c.File)
+		}
+		files = c.files(files)
+	}
+	return files
+}
+
+// htmlFileID is the anchor of the section of path. It is unique among the
+// ids already taken.
+func htmlFileID(path string, ids map[string]bool) string {
+	base := "file-" + strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return '_'
 		}
-		htmlBuilder.WriteString(line + "\n")
-	}
-	_, err := fmt.Fprintf(w, tmpl, htmlBuilder.String())
-	return err
-
-}
-
-func HTMLDir(diffs []diff.DirectoryDiff, w io.Writer) error {
-	const tmpl = `<!DOCTYPE html>
-<html>
-<head>
-<meta charset="UTF-8">
-<title>diff-dance - Directory Diff</title>
-<style>
-body { font-family: monospace; }
-.add { color: green; }
-.remove { color: red; }
-.change { color: orange; }
-.same { color: black; }
-.binary { color: magenta; }
-.add_dir { color: blue; }
-.remove_dir { color: blue; }
-.same_dir { color: gray; }
-</style>
-</head>
-<body>
-<pre>
-%s
-</pre>
-</body>
-</html>`
-
-	var htmlBuilder strings.Builder
+		return r
+	}, path)
+	id := base
+	for i := 2; ids[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	ids[id] = true
+	return id
+}
 
+// lineCounts returns the number of lines added and removed by diffs, a
+// change counting as both.
+func lineCounts(diffs []diff.Diff) (added, removed int) {
 	for _, d := range diffs {
-		var line string
 		switch d.Type {
-		case "add":
-			line = fmt.Sprintf(`<span class="add">+ %s</span>`, html.EscapeString(d.File2))
-		case "remove":
-			line = fmt.Sprintf(`<span class="remove">- %s</span>`, html.EscapeString(d.File1))
+		case "add", "move_to":
+			added++
+		case "remove", "move_from":
+			removed++
 		case "change":
-			if d.BinaryDiff { //Binary diff
-				line = fmt.Sprintf(`<span class="binary">~ Binary files differ: %s &lt;-&gt; %s</span>`, html.EscapeString(d.File1), html.EscapeString(d.File2))
-			} else { // Normal diff
-				line = fmt.Sprintf(`<span class="change">~ %s</span>`+"\n", html.EscapeString(d.File1))
-				for _, innerDiff := range d.Diffs { // Iterate the changes
-					switch innerDiff.Type {
-					case "add":
-						line += fmt.Sprintf(`<span class="add">+ %s</span>`+"\n", html.EscapeString(innerDiff.Line))
-					case "remove":
-						line += fmt.Sprintf(`<span class="remove">- %s</span>`+"\n", html.EscapeString(innerDiff.Line))
-					case "same":
-						line += fmt.Sprintf(`<span class="context">  %s</span>`+"\n", html.EscapeString(innerDiff.Line))
-
-					}
-				}
-			}
-		case "same":
-			line = fmt.Sprintf(`<span class="same">  %s</span>`, html.EscapeString(d.File1))
-		case "add_dir":
-			line = fmt.Sprintf(`<span class="add_dir">+ Directory added: %s</span>`, html.EscapeString(d.File2))
-		case "remove_dir":
-			line = fmt.Sprintf(`<span class="remove_dir">- Directory removed: %s</span>`, html.EscapeString(d.File1))
-		case "same_dir":
-			line = fmt.Sprintf(`<span class="same_dir">  Directory: %s</span>`, html.EscapeString(d.File1))
+			added++
+			removed++
 		}
-		htmlBuilder.WriteString(line + "\n")
 	}
-
-	_, err := fmt.Fprintf(w, tmpl, htmlBuilder.String())
-	return err
+	return added, removed
 }
@@ -0,0 +1,296 @@
+package display
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+func TestHTML(t *testing.T) {
+	lines := func(from, to int) string {
+		var b strings.Builder
+		for i := from; i <= to; i++ {
+			b.WriteString(strings.Repeat("x", i) + "\n")
+		}
+		return b.String()
+	}
+	tests := []struct {
+		name    string
+		input1  string
+		input2  string
+		search  *regexp.Regexp
+		want    []string
+		notWant []string
+	}{
+		{
+			name:   "identical",
+			input1: "a\nb\n",
+			input2: "a\nb\n",
+			want:   []string{`<p class="identical">No differences.</p>`, "Show 2 hidden lines"},
+		},
+		{
+			name:   "collapsed gaps",
+			input1: lines(1, 20),
+			input2: lines(1, 9) + "changed\n" + lines(11, 20),
+			want: []string{
+				`<button data-gap="split-gap-0">Show 6 hidden lines</button>`,
+				`<button data-gap="unified-gap-0">Show 6 hidden lines</button>`,
+				`<tbody id="split-gap-0" hidden>`,
+				`<button data-gap="split-gap-2">Show 7 hidden lines</button>`,
+				`<tr class="hunk-header"><td colspan="4">@@ -7,7 &#43;7,7 @@</td></tr>`,
+				`<tr class="hunk-header"><td colspan="3">@@ -7,7 &#43;7,7 @@</td></tr>`,
+			},
+			notWant: []string{"No differences"},
+		},
+		{
+			name:   "split and unified rows",
+			input1: "a\nold b\nc\n",
+			input2: "a\nnew b\nc\nd\n",
+			want: []string{
+				`<td class="lineno">2</td><td class="remove"><del>old</del> b</td><td class="lineno">2</td><td class="add"><ins>new</ins> b</td>`,
+				`<td class="lineno"></td><td class="empty"></td><td class="lineno">4</td><td class="add">d</td>`,
+				`<td class="lineno">2</td><td class="lineno"></td><td class="remove"><span class="sign">-</span><del>old</del> b</td>`,
+				`<td class="lineno">1</td><td class="lineno">1</td><td class="context"><span class="sign"> </span>a</td>`,
+			},
+		},
+		{
+			name:   "escaped and highlighted",
+			input1: "a <b>\n",
+			input2: "a <b> c\n",
+			search: regexp.MustCompile(`b`),
+			want:   []string{`a &lt;<mark>b</mark>&gt;<ins> c</ins>`},
+		},
+		{
+			name:   "moved block",
+			input1: "a\nm1\nm2\nm3\nb\nc\nd\n",
+			input2: "a\nb\nc\nd\nm1\nm2\nm3\n",
+			want: []string{
+				`<a id="split-move-1-from"></a>m1 <a class="move-link" href="#split-move-1-to">moved to line 5</a>`,
+				`<a id="unified-move-1-to"></a>m1 <a class="move-link" href="#unified-move-1-from">moved from line 2</a>`,
+			},
+		},
+	}
+
+	badAttribute := regexp.MustCompile(`class="[^"]*[<>]`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := diff.Files(strings.NewReader(tt.input1), strings.NewReader(tt.input2), diff.Options{})
+			if err != nil {
+				t.Fatalf("Files() error = %v", err)
+			}
+			var b strings.Builder
+			if err := HTMLWithHighlight(diffs, &b, tt.search, Options{Context: 3, IntraLine: "word", MoveLines: 3}); err != nil {
+				t.Fatalf("HTMLWithHighlight() error = %v", err)
+			}
+			got := b.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("HTMLWithHighlight() is missing %q in\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("HTMLWithHighlight() contains %q", notWant)
+				}
+			}
+			if m := badAttribute.FindString(got); m != "" {
+				t.Errorf("HTMLWithHighlight() has an unterminated attribute %q", m)
+			}
+		})
+	}
+}
+
+func TestHTMLSegments(t *testing.T) {
+	tests := []struct {
+		name           string
+		input1, input2 string
+		context        int
+		want           []string
+	}{
+		{name: "gaps around a hunk", input1: "a\nb\nc\nd\ne\n", input2: "a\nb\nx\nd\ne\n", context: 1, want: []string{"a", "@@ b c x d", "e"}},
+		{name: "addition first", input1: "b\nc\nd\n", input2: "a\nb\nc\nd\ny\n", context: 0, want: []string{"@@ a", "b c d", "@@ y"}},
+		{name: "removal after a gap", input1: "a\nb\nc\n", input2: "a\nb\n", context: 0, want: []string{"a b", "@@ c"}},
+		{name: "repeated lines", input1: "a\na\na\na\n", input2: "a\na\na\nb\na\n", context: 1, want: []string{"a a", "@@ a b a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := diff.Files(strings.NewReader(tt.input1), strings.NewReader(tt.input2), diff.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, s := range htmlSegments(diffs, tt.context) {
+				var lines []string
+				if s.hunk != nil {
+					lines = append(lines, "@@")
+				}
+				for _, d := range s.lines {
+					lines = append(lines, d.Line)
+				}
+				got = append(got, strings.Join(lines, " "))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("htmlSegments() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLDir(t *testing.T) {
+	changed, err := diff.Files(strings.NewReader("a\nb\nc\nd\ne\nf\ng\nh\ni\n"), strings.NewReader("a\nb\nc\nd\ne\nf\ng\nh\nx\ny\n"), diff.Options{})
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	diffs := []diff.DirectoryDiff{
+		{File2: "new", Type: "add_dir"},
+		{File1: "src", File2: "src", Type: "same_dir"},
+		{File2: "new/b.txt", Type: "add"},
+		{File1: "src/a.txt", File2: "src/a.txt", Type: "change", Diffs: changed},
+		{File1: "src/bin", File2: "src/bin", Type: "change", BinaryDiff: true},
+		{File1: "old file.txt", Type: "remove"},
+		{File1: "same.txt", File2: "same.txt", Type: "same"},
+	}
+	var b strings.Builder
+	if err := HTMLDir(diffs, &b, Options{Context: 3}); err != nil {
+		t.Fatalf("HTMLDir() error = %v", err)
+	}
+	got := b.String()
+
+	for _, want := range []string{
+		`<p class="summary">4 files differ: 2 changed, 1 added, 1 removed, 1 unchanged; <span class="added">+2</span> <span class="removed">-1</span> lines</p>`,
+		`<li class="dir" data-path="new" data-status="add"><details open><summary><span class="status add">+</span> new/</summary>`,
+		`<li data-path="src/a.txt" data-status="change"><a href="#file-src%2fa.txt"><span class="status change">~</span> a.txt</a> <span class="stats"><span class="added">+2</span> <span class="removed">-1</span></span></li>`,
+		`<li data-path="old file.txt" data-status="remove"><a href="#file-old_file.txt">`,
+		`<details class="file" id="file-src/a.txt" data-path="src/a.txt" data-status="change" open>`,
+		`<button data-gap="file-src/a.txt-split-gap-0">Show 5 hidden lines</button>`,
+		`<p class="note">Binary files differ.</p>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("HTMLDir() is missing %q in\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "same.txt") {
+		t.Errorf("HTMLDir() lists an unchanged file")
+	}
+
+	// Sections follow the tree: directories first, then by name.
+	var order []string
+	for _, line := range strings.Split(got, "\n") {
+		if _, rest, ok := strings.Cut(line, `<details class="file" id="`); ok {
+			id, _, _ := strings.Cut(rest, `"`)
+			order = append(order, id)
+		}
+	}
+	want := "file-new/b.txt file-src/a.txt file-src/bin file-old_file.txt"
+	if strings.Join(order, " ") != want {
+		t.Errorf("HTMLDir() sections = %q, want %q", order, want)
+	}
+}
+
+func TestHTMLFileID(t *testing.T) {
+	ids := make(map[string]bool)
+	for _, tt := range []struct{ path, want string }{
+		{"a.txt", "file-a.txt"},
+		{"dir/b c.txt", "file-dir/b_c.txt"},
+		{"dir/b_c.txt", "file-dir/b_c.txt-2"},
+		{"dir/b\tc.txt", "file-dir/b_c.txt-3"},
+	} {
+		if got := htmlFileID(tt.path, ids); got != tt.want {
+			t.Errorf("htmlFileID(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHTMLCustomization(t *testing.T) {
+	dir := t.TempDir()
+	templates := filepath.Join(dir, "templates")
+	css := filepath.Join(dir, "site.css")
+	files := map[string]string{
+		filepath.Join(templates, "nav.html"): `{{define "toolbar"}}<nav class="site">Docs</nav>` + "\n{{end}}\n",
+		css:                                  "body { font-family: serif; }\n",
+	}
+	if err := os.Mkdir(templates, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		want    []string
+		notWant []string
+		wantErr string
+	}{
+		{
+			name:    "default",
+			want:    []string{"--bg: #ffffff;", `<button id="toggle-view">`},
+			notWant: []string{"--bg: #0d1117;"},
+		},
+		{
+			name:    "dark theme",
+			opts:    Options{Theme: "dark"},
+			want:    []string{"--bg: #0d1117;"},
+			notWant: []string{"--bg: #ffffff;"},
+		},
+		{
+			name: "custom theme",
+			opts: Options{Theme: css},
+			want: []string{"--bg: #ffffff;", "body { font-family: serif; }"},
+		},
+		{
+			name:    "template override",
+			opts:    Options{Template: templates},
+			want:    []string{`<nav class="site">Docs</nav>`, `<table class="diff split">`},
+			notWant: []string{`<button id="toggle-view">`},
+		},
+		{
+			name:    "unknown theme",
+			opts:    Options{Theme: "drak"},
+			wantErr: `unknown theme "drak"`,
+		},
+		{
+			name:    "missing template directory",
+			opts:    Options{Template: filepath.Join(dir, "missing")},
+			wantErr: "loading templates",
+		},
+	}
+
+	diffs, err := diff.Files(strings.NewReader("a\n"), strings.NewReader("b\n"), diff.Options{})
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			err := HTML(diffs, &b, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("HTML() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("HTML() error = %v", err)
+			}
+			got := b.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("HTML() is missing %q", want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("HTML() contains %q", notWant)
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package display
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strconv"
@@ -13,7 +14,7 @@ import (
 	"github.com/san-kum/diff-dance/pkg/utils"
 )
 
-func Interactive(file1Path, file2Path string) {
+func Interactive(file1, file2 io.ReadSeeker, opts diff.Options, displayOpts Options) {
 	app := tview.NewApplication()
 
 	// --- Shared Variables ---
@@ -21,6 +22,7 @@ func Interactive(file1Path, file2Path string) {
 		diffs            []diff.Diff
 		file1Lines       []string
 		file2Lines       []string
+		links            map[int]moveLink
 		searchRegex      *regexp.Regexp
 		searchText       string
 		currentHighlight int
@@ -41,19 +43,25 @@ func Interactive(file1Path, file2Path string) {
 			d := diffs[index]
 			switch d.Type {
 			case "add":
-				detailText = fmt.Sprintf("Added:\n%s", d.Line)
+				detailText = fmt.Sprintf("Added at file 2 line %d:\n%s", d.NewLine, d.Line)
 			case "remove":
-				detailText = fmt.Sprintf("Removed:\n%s", d.Line)
+				detailText = fmt.Sprintf("Removed from file 1 line %d:\n%s", d.OldLine, d.Line)
+			case "change":
+				detailText = fmt.Sprintf("Changed:\nFile 1 line %d: %s\nFile 2 line %d: %s", d.OldLine, d.OldText, d.NewLine, d.Line)
+			case "move_from":
+				detailText = fmt.Sprintf("Moved from file 1 line %d (block starting at file 2 line %d):\n%s", d.OldLine, links[d.MoveID].newLine, d.Line)
+			case "move_to":
+				detailText = fmt.Sprintf("Moved to file 2 line %d (block starting at file 1 line %d):\n%s", d.NewLine, links[d.MoveID].oldLine, d.Line)
 			case "same":
 				line1 := ""
-				if index < len(file1Lines) {
-					line1 = file1Lines[index]
+				if d.OldLine > 0 && d.OldLine <= len(file1Lines) {
+					line1 = file1Lines[d.OldLine-1]
 				}
 				line2 := ""
-				if index < len(file2Lines) {
-					line2 = file2Lines[index]
+				if d.NewLine > 0 && d.NewLine <= len(file2Lines) {
+					line2 = file2Lines[d.NewLine-1]
 				}
-				detailText = fmt.Sprintf("Context:\nFile 1: %s\nFile 2: %s", line1, line2)
+				detailText = fmt.Sprintf("Context:\nFile 1 line %d: %s\nFile 2 line %d: %s", d.OldLine, line1, d.NewLine, line2)
 			}
 		}
 
@@ -90,23 +98,23 @@ func Interactive(file1Path, file2Path string) {
 
 		// Forward search
 		for i := start; i < len(diffs); i++ {
-			if searchRegex.MatchString(diffs[i].Line) {
+			if searchRegex.MatchString(diffs[i].Line) || searchRegex.MatchString(diffs[i].OldText) {
 				currentHighlight = i
 				textView.Highlight(strconv.Itoa(currentHighlight)).ScrollToHighlight()
-				textView.SetText(buildInteractiveDiffText(diffs, searchRegex))
+				textView.SetText(buildInteractiveDiffText(diffs, searchRegex, displayOpts))
 				return true
 			}
 		}
 
 		for i := 0; i < start; i++ {
-			if searchRegex.MatchString(diffs[i].Line) {
+			if searchRegex.MatchString(diffs[i].Line) || searchRegex.MatchString(diffs[i].OldText) {
 				currentHighlight = i
 				textView.Highlight(strconv.Itoa(currentHighlight)).ScrollToHighlight()
-				textView.SetText(buildInteractiveDiffText(diffs, searchRegex))
+				textView.SetText(buildInteractiveDiffText(diffs, searchRegex, displayOpts))
 				return true
 			}
 		}
-		textView.SetText(buildInteractiveDiffText(diffs, nil))
+		textView.SetText(buildInteractiveDiffText(diffs, nil, displayOpts))
 		return false
 	}
 
@@ -220,21 +228,7 @@ func Interactive(file1Path, file2Path string) {
 	})
 
 	// --- Load and Diff Files ---
-	file1, err := os.Open(file1Path)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening file1: %v\n", err)
-		os.Exit(1)
-	}
-	defer file1.Close()
-
-	file2, err := os.Open(file2Path)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening file2: %v\n", err)
-		os.Exit(1)
-	}
-	defer file2.Close()
-
-	file1Lines, err = utils.ReadLines(file1)
+	file1Lines, err := utils.ReadLines(file1)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading lines from file1: %v\n", err)
 		os.Exit(1)
@@ -245,16 +239,18 @@ func Interactive(file1Path, file2Path string) {
 		os.Exit(1)
 	}
 
-	file1.Seek(0, 0)
-	file2.Seek(0, 0)
+	file1.Seek(0, io.SeekStart)
+	file2.Seek(0, io.SeekStart)
 
-	diffs, err = diff.Files(file1, file2)
+	diffs, err = diff.Files(file1, file2, opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error diffing files: %v\n", err)
 		os.Exit(1)
 	}
+	diffs = displayOpts.prepare(diffs)
+	links = moveLinks(diffs)
 
-	diffText := buildInteractiveDiffText(diffs, nil)
+	diffText := buildInteractiveDiffText(diffs, nil, displayOpts)
 	textView.SetText(diffText)
 	textView.Highlight(strconv.Itoa(currentHighlight)).ScrollToHighlight()
 
@@ -267,8 +263,9 @@ func Interactive(file1Path, file2Path string) {
 	}
 }
 
-func buildInteractiveDiffText(diffs []diff.Diff, searchRegex *regexp.Regexp) string {
+func buildInteractiveDiffText(diffs []diff.Diff, searchRegex *regexp.Regexp, opts Options) string {
 	var builder strings.Builder
+	links := moveLinks(diffs)
 	for i, d := range diffs {
 		regionTag := strconv.Itoa(i)
 		var line string
@@ -277,6 +274,15 @@ func buildInteractiveDiffText(diffs []diff.Diff, searchRegex *regexp.Regexp) str
 			line = fmt.Sprintf(`[green]+ %s[white]`, d.Line)
 		case "remove":
 			line = fmt.Sprintf(`[red]- %s[white]`, d.Line)
+		case "change":
+			oldSpans, newSpans := diff.IntraLine(d.OldText, d.Line, opts.IntraLine)
+			line = fmt.Sprintf("[red]- %s[white]\n[green]+ %s[white]",
+				markSpans(d.OldText, oldSpans, tviewReverse, identity),
+				markSpans(d.Line, newSpans, tviewReverse, identity))
+		case "move_from":
+			line = fmt.Sprintf(`[fuchsia]- %s[white]`, d.Line)
+		case "move_to":
+			line = fmt.Sprintf(`[aqua]+ %s[white]`, d.Line)
 		case "same":
 			line = fmt.Sprintf(`  %s`, d.Line)
 		}
@@ -286,9 +292,17 @@ func buildInteractiveDiffText(diffs []diff.Diff, searchRegex *regexp.Regexp) str
 				return fmt.Sprintf("[yellow::b]%s[white]", match) // Yellow background, bold
 			})
 		}
+		if note := moveNote(d, links); note != "" {
+			line += fmt.Sprintf("  [gray](%s)[white]", note)
+		}
 
 		builder.WriteString(fmt.Sprintf(`["%s"]%s[""]`, regionTag, line))
 		builder.WriteString("\n")
 	}
 	return builder.String()
 }
+
+// tviewReverse shows s in reverse video using tview's style tags.
+func tviewReverse(s string) string {
+	return "[::r]" + s + "[::-]"
+}
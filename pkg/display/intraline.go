@@ -0,0 +1,30 @@
+package display
+
+import (
+	"strings"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+// markSpans renders s, passing the changed spans through mark and the rest
+// through plain.
+func markSpans(s string, spans []diff.Span, mark, plain func(string) string) string {
+	var b strings.Builder
+	pos := 0
+	for _, sp := range spans {
+		b.WriteString(plain(s[pos:sp.Start]))
+		b.WriteString(mark(s[sp.Start:sp.End]))
+		pos = sp.End
+	}
+	b.WriteString(plain(s[pos:]))
+	return b.String()
+}
+
+func identity(s string) string {
+	return s
+}
+
+// reverse shows s in reverse video without resetting the surrounding color.
+func reverse(s string) string {
+	return "\033[7m" + s + "\033[27m"
+}
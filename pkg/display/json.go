@@ -0,0 +1,230 @@
+package display
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+// JSONVersion is the version of the JSON output, whose schema is in
+// schema.json. It is raised whenever a field is removed or changes
+// meaning; fields may be added without raising it.
+const JSONVersion = 1
+
+// JSONFile is the JSON output of a file diff.
+type JSONFile struct {
+	Version int        `json:"version"`
+	Kind    string     `json:"kind"`
+	Old     string     `json:"old"`
+	New     string     `json:"new"`
+	Hunks   []JSONHunk `json:"hunks"`
+}
+
+// JSONHunk is a hunk of a file diff. The ranges are those of its unified
+// diff header.
+type JSONHunk struct {
+	OldStart int        `json:"old_start"`
+	OldLines int        `json:"old_lines"`
+	NewStart int        `json:"new_start"`
+	NewLines int        `json:"new_lines"`
+	Lines    []JSONLine `json:"lines"`
+}
+
+// JSONLine is a diff.Diff. Line numbers are left out on the side where the
+// line does not exist.
+type JSONLine struct {
+	Type      string `json:"type"`
+	Text      string `json:"text"`
+	OldText   string `json:"old_text,omitempty"`
+	OldLine   int    `json:"old_line,omitempty"`
+	NewLine   int    `json:"new_line,omitempty"`
+	NoNewline bool   `json:"no_newline,omitempty"`
+	MoveID    int    `json:"move_id,omitempty"`
+}
+
+// JSONDirectory is the JSON output of a directory diff. In NDJSON output
+// it is the first line, without Files, and every entry follows on a line of
+// its own.
+type JSONDirectory struct {
+	Version int         `json:"version"`
+	Kind    string      `json:"kind"`
+	Old     string      `json:"old"`
+	New     string      `json:"new"`
+	Files   []JSONEntry `json:"files,omitempty"`
+}
+
+// JSONEntry is a diff.DirectoryDiff. Status is its Type.
+type JSONEntry struct {
+	Status     string     `json:"status"`
+	OldPath    string     `json:"old_path,omitempty"`
+	NewPath    string     `json:"new_path,omitempty"`
+	Binary     bool       `json:"binary,omitempty"`
+	OldModTime time.Time  `json:"old_mtime,omitzero"`
+	NewModTime time.Time  `json:"new_mtime,omitzero"`
+	Hunks      []JSONHunk `json:"hunks,omitempty"`
+}
+
+// JSONStructural is the JSON output of a structural diff.
+type JSONStructural struct {
+	Version int                    `json:"version"`
+	Kind    string                 `json:"kind"`
+	Changes []JSONStructuralChange `json:"changes"`
+}
+
+// JSONStructuralChange is a diff.StructuralDiff.
+type JSONStructuralChange struct {
+	Type         string `json:"type"`
+	Name         string `json:"name"`
+	OldSignature string `json:"old_signature,omitempty"`
+	NewSignature string `json:"new_signature,omitempty"`
+}
+
+// JSON writes diffs between the files old and new as a JSONFile, indented
+// unless compact is set. The hunks keep opts.Context lines of context; a
+// negative Context puts every line in a single hunk.
+func JSON(diffs []diff.Diff, old, new string, w io.Writer, compact bool, opts Options) error {
+	return writeJSON(w, compact, JSONFile{
+		Version: JSONVersion,
+		Kind:    "file",
+		Old:     old,
+		New:     new,
+		Hunks:   jsonHunks(opts.prepare(diffs), opts),
+	})
+}
+
+// JSONDir writes the diff of the directories dir1 and dir2 as a
+// JSONDirectory, indented unless compact is set.
+func JSONDir(diffs []diff.DirectoryDiff, dir1, dir2 string, w io.Writer, compact bool, opts Options) error {
+	out := JSONDirectory{Version: JSONVersion, Kind: "directory", Old: dir1, New: dir2}
+	for _, d := range diffs {
+		out.Files = append(out.Files, jsonEntry(d, opts))
+	}
+	return writeJSON(w, compact, out)
+}
+
+// JSONStructuralDiffs writes diffs as a JSONStructural, indented unless
+// compact is set.
+func JSONStructuralDiffs(diffs []diff.StructuralDiff, w io.Writer, compact bool) error {
+	out := JSONStructural{Version: JSONVersion, Kind: "structural", Changes: []JSONStructuralChange{}}
+	for _, d := range diffs {
+		out.Changes = append(out.Changes, JSONStructuralChange{Type: d.Type, Name: d.FuncName, OldSignature: d.OldSig, NewSignature: d.NewSig})
+	}
+	return writeJSON(w, compact, out)
+}
+
+// NDJSONWriter writes a directory diff as newline-delimited JSON, so that
+// entries can be written, and read, one at a time.
+type NDJSONWriter struct {
+	enc  *json.Encoder
+	opts Options
+}
+
+// NewNDJSONWriter returns an NDJSONWriter that has written the header line
+// for the diff of dir1 and dir2 to w.
+func NewNDJSONWriter(w io.Writer, dir1, dir2 string, opts Options) (*NDJSONWriter, error) {
+	n := &NDJSONWriter{enc: json.NewEncoder(w), opts: opts}
+	n.enc.SetEscapeHTML(false)
+	if err := n.enc.Encode(JSONDirectory{Version: JSONVersion, Kind: "directory", Old: dir1, New: dir2}); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Write writes the line of d.
+func (n *NDJSONWriter) Write(d diff.DirectoryDiff) error {
+	return n.enc.Encode(jsonEntry(d, n.opts))
+}
+
+func writeJSON(w io.Writer, compact bool, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if !compact {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
+
+func jsonHunks(diffs []diff.Diff, opts Options) []JSONHunk {
+	hunks := []JSONHunk{}
+	for _, h := range diff.Hunks(diffs, opts.Context) {
+		jh := JSONHunk{OldStart: h.OldStart, OldLines: h.OldLines, NewStart: h.NewStart, NewLines: h.NewLines}
+		for _, d := range h.Lines {
+			jh.Lines = append(jh.Lines, JSONLine{
+				Type:      d.Type,
+				Text:      d.Line,
+				OldText:   d.OldText,
+				OldLine:   d.OldLine,
+				NewLine:   d.NewLine,
+				NoNewline: d.NoNewline,
+				MoveID:    d.MoveID,
+			})
+		}
+		hunks = append(hunks, jh)
+	}
+	return hunks
+}
+
+func jsonEntry(d diff.DirectoryDiff, opts Options) JSONEntry {
+	e := JSONEntry{
+		Status:     d.Type,
+		OldPath:    d.File1,
+		NewPath:    d.File2,
+		Binary:     d.BinaryDiff,
+		OldModTime: d.ModTime1,
+		NewModTime: d.ModTime2,
+	}
+	if len(d.Diffs) > 0 {
+		e.Hunks = jsonHunks(opts.prepare(d.Diffs), opts)
+	}
+	return e
+}
+
+// Diffs returns the lines of the hunks of f. With every line in a hunk, as
+// JSON writes them for a negative Context, they are the diffs it was
+// written from.
+func (f JSONFile) Diffs() []diff.Diff {
+	return jsonDiffs(f.Hunks)
+}
+
+// DirectoryDiff returns the entry e was written from, with the lines of
+// its hunks as Diffs.
+func (e JSONEntry) DirectoryDiff() diff.DirectoryDiff {
+	return diff.DirectoryDiff{
+		File1:      e.OldPath,
+		File2:      e.NewPath,
+		Type:       e.Status,
+		Diffs:      jsonDiffs(e.Hunks),
+		BinaryDiff: e.Binary,
+		ModTime1:   e.OldModTime,
+		ModTime2:   e.NewModTime,
+	}
+}
+
+// StructuralDiffs returns the diffs s was written from.
+func (s JSONStructural) StructuralDiffs() []diff.StructuralDiff {
+	var diffs []diff.StructuralDiff
+	for _, c := range s.Changes {
+		diffs = append(diffs, diff.StructuralDiff{Type: c.Type, FuncName: c.Name, OldSig: c.OldSignature, NewSig: c.NewSignature})
+	}
+	return diffs
+}
+
+func jsonDiffs(hunks []JSONHunk) []diff.Diff {
+	var diffs []diff.Diff
+	for _, h := range hunks {
+		for _, l := range h.Lines {
+			diffs = append(diffs, diff.Diff{
+				Line:      l.Text,
+				Type:      l.Type,
+				OldLine:   l.OldLine,
+				NewLine:   l.NewLine,
+				NoNewline: l.NoNewline,
+				OldText:   l.OldText,
+				MoveID:    l.MoveID,
+			})
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,243 @@
+package display
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		input1 string
+		input2 string
+		opts   Options
+	}{
+		{name: "changed line", input1: "a\nb\nc\n", input2: "a\nx\nc\n"},
+		{name: "missing newline", input1: "a\nb", input2: "a\nb\n"},
+		{name: "paired change", input1: "a\nold b\n", input2: "a\nnew b\n", opts: Options{IntraLine: "word"}},
+		{name: "moved block", input1: "a\nm1\nm2\nm3\nb\n", input2: "a\nb\nm1\nm2\nm3\n", opts: Options{MoveLines: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := diff.Files(strings.NewReader(tt.input1), strings.NewReader(tt.input2), diff.Options{})
+			if err != nil {
+				t.Fatalf("Files() error = %v", err)
+			}
+			opts := tt.opts
+			opts.Context = -1
+			var b strings.Builder
+			if err := JSON(diffs, "old", "new", &b, false, opts); err != nil {
+				t.Fatalf("JSON() error = %v", err)
+			}
+			var got JSONFile
+			if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got.Version != JSONVersion || got.Kind != "file" || got.Old != "old" || got.New != "new" {
+				t.Errorf("JSON() header = %d %q %q %q", got.Version, got.Kind, got.Old, got.New)
+			}
+			if want := opts.prepare(diffs); !reflect.DeepEqual(got.Diffs(), want) {
+				t.Errorf("JSON() round trip = %v, want %v", got.Diffs(), want)
+			}
+		})
+	}
+}
+
+func TestJSONHunks(t *testing.T) {
+	diffs, err := diff.Files(strings.NewReader("1\n2\n3\n4\n5\n6\n7\n8\n9\n"), strings.NewReader("1\nx\n3\n4\n5\n6\n7\n8\ny\n"), diff.Options{})
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	var b strings.Builder
+	if err := JSON(diffs, "old", "new", &b, true, Options{Context: 1}); err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	want := `{"version":1,"kind":"file","old":"old","new":"new","hunks":[` +
+		`{"old_start":1,"old_lines":3,"new_start":1,"new_lines":3,"lines":[{"type":"same","text":"1","old_line":1,"new_line":1},{"type":"remove","text":"2","old_line":2},{"type":"add","text":"x","new_line":2},{"type":"same","text":"3","old_line":3,"new_line":3}]},` +
+		`{"old_start":8,"old_lines":2,"new_start":8,"new_lines":2,"lines":[{"type":"same","text":"8","old_line":8,"new_line":8},{"type":"remove","text":"9","old_line":9},{"type":"add","text":"y","new_line":9}]}]}` + "\n"
+	if b.String() != want {
+		t.Errorf("JSON() =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	if err := JSON(diffs[:1], "old", "new", &b, true, Options{}); err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	if want := `"hunks":[]`; !strings.Contains(b.String(), want) {
+		t.Errorf("JSON() of identical files = %s, want %s", b.String(), want)
+	}
+}
+
+func TestJSONDirRoundTrip(t *testing.T) {
+	changed, err := diff.Files(strings.NewReader("a\nb\n"), strings.NewReader("a\nc\n"), diff.Options{})
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	mtime := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	diffs := []diff.DirectoryDiff{
+		{File1: "dir", File2: "dir", Type: "same_dir"},
+		{File2: "new", Type: "add_dir"},
+		{File1: "dir/a.txt", File2: "dir/a.txt", Type: "change", Diffs: changed, ModTime1: mtime, ModTime2: mtime.Add(time.Hour)},
+		{File1: "bin", File2: "bin", Type: "change", BinaryDiff: true, ModTime1: mtime, ModTime2: mtime},
+		{File1: "gone.txt", Type: "remove"},
+		{File1: "same.txt", File2: "same.txt", Type: "same", ModTime1: mtime, ModTime2: mtime},
+	}
+	opts := Options{Context: -1}
+
+	var b strings.Builder
+	if err := JSONDir(diffs, "old", "new", &b, false, opts); err != nil {
+		t.Fatalf("JSONDir() error = %v", err)
+	}
+	var got JSONDirectory
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.Version != JSONVersion || got.Kind != "directory" || got.Old != "old" || got.New != "new" {
+		t.Errorf("JSONDir() header = %d %q %q %q", got.Version, got.Kind, got.Old, got.New)
+	}
+	var roundTrip []diff.DirectoryDiff
+	for _, e := range got.Files {
+		roundTrip = append(roundTrip, e.DirectoryDiff())
+	}
+	if !reflect.DeepEqual(roundTrip, diffs) {
+		t.Errorf("JSONDir() round trip =\n%v\nwant\n%v", roundTrip, diffs)
+	}
+
+	// NDJSON holds the same entries, one per line after the header.
+	b.Reset()
+	out, err := NewNDJSONWriter(&b, "old", "new", opts)
+	if err != nil {
+		t.Fatalf("NewNDJSONWriter() error = %v", err)
+	}
+	for _, d := range diffs {
+		if err := out.Write(d); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	scanner := bufio.NewScanner(strings.NewReader(b.String()))
+	if !scanner.Scan() {
+		t.Fatal("NDJSON output is empty")
+	}
+	var header JSONDirectory
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		t.Fatalf("Unmarshal() of the header error = %v", err)
+	}
+	if want := (JSONDirectory{Version: JSONVersion, Kind: "directory", Old: "old", New: "new"}); !reflect.DeepEqual(header, want) {
+		t.Errorf("NDJSON header = %+v, want %+v", header, want)
+	}
+	var entries []JSONEntry
+	for scanner.Scan() {
+		var e JSONEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Unmarshal() of %s error = %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	if !reflect.DeepEqual(entries, got.Files) {
+		t.Errorf("NDJSON entries =\n%v\nwant\n%v", entries, got.Files)
+	}
+}
+
+func TestJSONStructuralRoundTrip(t *testing.T) {
+	diffs := []diff.StructuralDiff{
+		{Type: "add_func", FuncName: "New"},
+		{Type: "change_func_sig", FuncName: "Run", OldSig: "func Run()", NewSig: "func Run(ctx context.Context)"},
+	}
+	var b strings.Builder
+	if err := JSONStructuralDiffs(diffs, &b, false); err != nil {
+		t.Fatalf("JSONStructuralDiffs() error = %v", err)
+	}
+	var got JSONStructural
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.Version != JSONVersion || got.Kind != "structural" {
+		t.Errorf("JSONStructuralDiffs() header = %d %q", got.Version, got.Kind)
+	}
+	if !reflect.DeepEqual(got.StructuralDiffs(), diffs) {
+		t.Errorf("JSONStructuralDiffs() round trip = %v, want %v", got.StructuralDiffs(), diffs)
+	}
+}
+
+// TestJSONSchema checks that schema.json describes the fields the JSON
+// types write, and which of them are always there.
+func TestJSONSchema(t *testing.T) {
+	content, err := os.ReadFile("schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type object struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+		Items      *object                    `json:"items"`
+	}
+	var schema struct {
+		Defs map[string]json.RawMessage `json:"$defs"`
+	}
+	if err := json.Unmarshal(content, &schema); err != nil {
+		t.Fatalf("schema.json is not valid JSON: %v", err)
+	}
+	def := func(name string) object {
+		var o object
+		if err := json.Unmarshal(schema.Defs[name], &o); err != nil {
+			t.Fatalf("schema.json %s: %v", name, err)
+		}
+		return o
+	}
+
+	var version struct {
+		Const int `json:"const"`
+	}
+	if err := json.Unmarshal(schema.Defs["version"], &version); err != nil || version.Const != JSONVersion {
+		t.Errorf("schema.json version = %d, want %d", version.Const, JSONVersion)
+	}
+
+	var changes object
+	if err := json.Unmarshal(def("structural").Properties["changes"], &changes); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		def    string
+		object object
+		typ    any
+	}{
+		{"file", def("file"), JSONFile{}},
+		{"hunk", def("hunk"), JSONHunk{}},
+		{"line", def("line"), JSONLine{}},
+		{"directory", def("directory"), JSONDirectory{}},
+		{"entry", def("entry"), JSONEntry{}},
+		{"structural", def("structural"), JSONStructural{}},
+		{"structural change", *changes.Items, JSONStructuralChange{}},
+	} {
+		var fields, required []string
+		typ := reflect.TypeOf(tt.typ)
+		for i := 0; i < typ.NumField(); i++ {
+			name, options, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			fields = append(fields, name)
+			if options == "" {
+				required = append(required, name)
+			}
+		}
+		var properties []string
+		for name := range tt.object.Properties {
+			properties = append(properties, name)
+		}
+		slices.Sort(fields)
+		slices.Sort(properties)
+		if !slices.Equal(fields, properties) {
+			t.Errorf("schema.json %s properties = %v, want %v", tt.def, properties, fields)
+		}
+		if !slices.Equal(required, tt.object.Required) {
+			t.Errorf("schema.json %s required = %v, want %v", tt.def, tt.object.Required, required)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+// markdownCollapseLines is the number of diff lines beyond which a file's
+// diff is folded into a <details> section.
+const markdownCollapseLines = 50
+
+// Markdown writes the diff of the files old and new as MarkdownDir does.
+// structural lists the changes to their Go functions, if any.
+func Markdown(diffs []diff.Diff, structural []diff.StructuralDiff, old, new string, w io.Writer, opts Options) error {
+	if !diff.HasChanges(diffs) {
+		return MarkdownDir(nil, nil, w, opts)
+	}
+	d := diff.DirectoryDiff{File1: old, File2: new, Type: "change", Diffs: diffs}
+	return MarkdownDir([]diff.DirectoryDiff{d}, map[string][]diff.StructuralDiff{old: structural}, w, opts)
+}
+
+// MarkdownDir writes a directory diff as a Markdown report to paste into
+// pull request comments: a table of the changed files with the number of
+// lines added and removed, a ```diff block for each file, cut off after
+// opts.MaxLines lines and folded into a <details> section when long, and
+// the changes to Go functions that structural holds by the old path of
+// each file.
+func MarkdownDir(diffs []diff.DirectoryDiff, structural map[string][]diff.StructuralDiff, w io.Writer, opts Options) error {
+	var b strings.Builder
+	var files []diff.DirectoryDiff
+	for _, d := range diffs {
+		if d.Type == "add" || d.Type == "remove" || d.Type == "change" {
+			files = append(files, d)
+		}
+	}
+	if len(files) == 0 {
+		b.WriteString("No differences.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	noun := "files"
+	if len(files) == 1 {
+		noun = "file"
+	}
+	fmt.Fprintf(&b, "### %d %s changed\n\n", len(files), noun)
+	b.WriteString("| File | Status | Added | Removed |\n| --- | --- | ---: | ---: |\n")
+	var added, removed int
+	for _, f := range files {
+		a, r := lineCounts(f.Diffs)
+		added += a
+		removed += r
+		counts := fmt.Sprintf("| %d | %d |", a, r)
+		if f.Type != "change" || f.BinaryDiff {
+			counts = "| | |"
+		}
+		fmt.Fprintf(&b, "| %s | %s %s\n", markdownTableCell(markdownCode(markdownPath(f))), markdownStatus(f), counts)
+	}
+	fmt.Fprintf(&b, "| **Total** | | **%d** | **%d** |\n", added, removed)
+
+	for _, f := range files {
+		if f.Type != "change" || f.BinaryDiff {
+			continue
+		}
+		writeMarkdownDiff(&b, f, opts)
+	}
+
+	var goFiles []string
+	for path, s := range structural {
+		if len(s) > 0 {
+			goFiles = append(goFiles, path)
+		}
+	}
+	if len(goFiles) > 0 {
+		sort.Strings(goFiles)
+		b.WriteString("\n### Structural changes\n")
+		for _, path := range goFiles {
+			fmt.Fprintf(&b, "\n%s\n\n", markdownCode(path))
+			for _, s := range structural[path] {
+				switch s.Type {
+				case "add_func":
+					fmt.Fprintf(&b, "- Added function %s\n", markdownCode(s.FuncName))
+				case "remove_func":
+					fmt.Fprintf(&b, "- Removed function %s\n", markdownCode(s.FuncName))
+				case "change_func_sig":
+					fmt.Fprintf(&b, "- Changed signature of %s from %s to %s\n", markdownCode(s.FuncName), markdownCode(s.OldSig), markdownCode(s.NewSig))
+				}
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMarkdownDiff writes the hunks of a changed file in a ```diff block.
+func writeMarkdownDiff(b *strings.Builder, f diff.DirectoryDiff, opts Options) {
+	var patch strings.Builder
+	for _, h := range diff.Hunks(f.Diffs, opts.Context) {
+		diff.WriteHunk(&patch, h)
+	}
+	lines := strings.SplitAfter(patch.String(), "\n")
+	lines = lines[:len(lines)-1]
+	shown := len(lines)
+	if opts.MaxLines > 0 {
+		shown = min(shown, opts.MaxLines)
+	}
+
+	title := markdownCode(markdownPath(f))
+	added, removed := lineCounts(f.Diffs)
+	collapse := len(lines) > markdownCollapseLines
+	if collapse {
+		fmt.Fprintf(b, "\n<details>\n<summary>%s (+%d -%d)</summary>\n\n", title, added, removed)
+	} else {
+		fmt.Fprintf(b, "\n#### %s\n\n", title)
+	}
+	fence := markdownFence(patch.String())
+	b.WriteString(fence + "diff\n")
+	b.WriteString(strings.Join(lines[:shown], ""))
+	b.WriteString(fence + "\n")
+	if shown < len(lines) {
+		fmt.Fprintf(b, "\n_Diff truncated: showing %d of %d lines._\n", shown, len(lines))
+	}
+	if collapse {
+		b.WriteString("\n</details>\n")
+	}
+}
+
+// markdownPath names the file of f, with both paths when they differ.
+func markdownPath(f diff.DirectoryDiff) string {
+	switch {
+	case f.File1 == "":
+		return f.File2
+	case f.File2 == "" || f.File1 == f.File2:
+		return f.File1
+	}
+	return f.File1 + " → " + f.File2
+}
+
+func markdownStatus(f diff.DirectoryDiff) string {
+	switch {
+	case f.Type == "add":
+		return "added"
+	case f.Type == "remove":
+		return "removed"
+	case f.BinaryDiff:
+		return "binary"
+	}
+	return "modified"
+}
+
+// markdownCode renders s as a code span, delimited by more backticks than
+// it contains in a row.
+func markdownCode(s string) string {
+	ticks := strings.Repeat("`", longestRun(s, '`')+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return ticks + s + ticks
+}
+
+// markdownFence is a code fence longer than any run of backticks in s.
+func markdownFence(s string) string {
+	return strings.Repeat("`", max(3, longestRun(s, '`')+1))
+}
+
+// markdownTableCell escapes the pipes that would end a table cell early.
+func markdownTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func longestRun(s string, c rune) int {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == c {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
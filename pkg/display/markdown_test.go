@@ -0,0 +1,128 @@
+package display
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+func TestMarkdownDir(t *testing.T) {
+	lines := func(prefix string, n int) string {
+		var b strings.Builder
+		for i := range n {
+			b.WriteString(prefix + strings.Repeat("x", i) + "\n")
+		}
+		return b.String()
+	}
+	fileDiffs := func(input1, input2 string) []diff.Diff {
+		diffs, err := diff.Files(strings.NewReader(input1), strings.NewReader(input2), diff.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return diffs
+	}
+	tests := []struct {
+		name       string
+		diffs      []diff.DirectoryDiff
+		structural map[string][]diff.StructuralDiff
+		opts       Options
+		want       []string
+		notWant    []string
+	}{
+		{
+			name:  "identical",
+			diffs: []diff.DirectoryDiff{{File1: "a.txt", File2: "a.txt", Type: "same"}},
+			want:  []string{"No differences.\n"},
+		},
+		{
+			name: "summary table",
+			diffs: []diff.DirectoryDiff{
+				{File1: "a|b.txt", File2: "a|b.txt", Type: "change", Diffs: fileDiffs("a\nb\n", "a\nc\nd\n")},
+				{File2: "new.txt", Type: "add"},
+				{File1: "img.png", File2: "img.png", Type: "change", BinaryDiff: true},
+				{File1: "dir", File2: "dir", Type: "same_dir"},
+			},
+			opts: Options{Context: 3},
+			want: []string{
+				"### 3 files changed\n",
+				"| `a\\|b.txt` | modified | 2 | 1 |\n",
+				"| `new.txt` | added | | |\n",
+				"| `img.png` | binary | | |\n",
+				"| **Total** | | **2** | **1** |\n",
+				"#### `a|b.txt`\n\n```diff\n@@ -1,2 +1,3 @@\n a\n-b\n+c\n+d\n```\n",
+			},
+			notWant: []string{"dir", "<details>", "truncated", "Structural"},
+		},
+		{
+			name:  "truncated and collapsed",
+			diffs: []diff.DirectoryDiff{{File1: "big.txt", File2: "big.txt", Type: "change", Diffs: fileDiffs(lines("a", 30), lines("b", 30))}},
+			opts:  Options{Context: 3, MaxLines: 10},
+			want: []string{
+				"<details>\n<summary>`big.txt` (+30 -30)</summary>\n",
+				"\n_Diff truncated: showing 10 of 61 lines._\n\n</details>\n",
+			},
+			notWant: []string{"#### "},
+		},
+		{
+			name:  "fence longer than the content's backticks",
+			diffs: []diff.DirectoryDiff{{File1: "a.md", File2: "a.md", Type: "change", Diffs: fileDiffs("```\n", "````\n")}},
+			want:  []string{"`````diff\n@@ -1 +1 @@\n-```\n+````\n`````\n"},
+		},
+		{
+			name:  "structural changes",
+			diffs: []diff.DirectoryDiff{{File1: "a.go", File2: "a.go", Type: "change", Diffs: fileDiffs("a\n", "b\n")}},
+			structural: map[string][]diff.StructuralDiff{
+				"a.go": {
+					{Type: "add_func", FuncName: "New"},
+					{Type: "remove_func", FuncName: "Old"},
+					{Type: "change_func_sig", FuncName: "Run", OldSig: "func Run()", NewSig: "func Run(ctx context.Context)"},
+				},
+				"b.go": nil,
+			},
+			want: []string{
+				"### Structural changes\n\n`a.go`\n\n",
+				"- Added function `New`\n",
+				"- Removed function `Old`\n",
+				"- Changed signature of `Run` from `func Run()` to `func Run(ctx context.Context)`\n",
+			},
+			notWant: []string{"b.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := MarkdownDir(tt.diffs, tt.structural, &b, tt.opts); err != nil {
+				t.Fatalf("MarkdownDir() error = %v", err)
+			}
+			got := b.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("MarkdownDir() = %q, want it to contain %q", got, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("MarkdownDir() = %q, want it not to contain %q", got, notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkdownCode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"a.go", "`a.go`"},
+		{"a`b", "``a`b``"},
+		{"`a``", "``` `a`` ```"},
+	}
+	for _, tt := range tests {
+		if got := markdownCode(tt.input); got != tt.want {
+			t.Errorf("markdownCode(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
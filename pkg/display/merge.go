@@ -0,0 +1,285 @@
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+// mergeColors are the tview colors of the merge region types.
+var mergeColors = map[string]string{
+	"ours":     "green",
+	"theirs":   "aqua",
+	"both":     "teal",
+	"conflict": "red",
+}
+
+const mergeHelp = "o: ours  t: theirs  b: both  e: edit  u: undo  n/p: next/previous  s: save  q: quit"
+
+// InteractiveMerge lets the user resolve the conflicts of m one by one in a
+// terminal UI showing ours, base and theirs side by side above the merged
+// result. Saving writes the result to outputPath. It reports whether the
+// last save wrote the current state with every conflict resolved, which is
+// what git mergetool expects as the exit status.
+func InteractiveMerge(m diff.Merge, outputPath string, style diff.MergeStyle) (bool, error) {
+	app := tview.NewApplication()
+
+	var conflicts []int
+	for i, r := range m.Regions {
+		if r.Type == "conflict" {
+			conflicts = append(conflicts, i)
+		}
+	}
+	var (
+		current int // index into conflicts
+		saved   bool
+		dirty   bool
+		message string
+	)
+
+	pane := func(title string) *tview.TextView {
+		tv := tview.NewTextView().SetDynamicColors(true).SetRegions(true).SetWrap(false)
+		tv.SetBorder(true).SetTitle(" " + title + " ")
+		return tv
+	}
+	oursView := pane("Ours: " + style.OursLabel)
+	baseView := pane("Base: " + style.BaseLabel)
+	theirsView := pane("Theirs: " + style.TheirsLabel)
+	resultView := pane("Result: " + outputPath)
+	status := tview.NewTextView().SetDynamicColors(true)
+
+	sides := tview.NewFlex().
+		AddItem(oursView, 0, 1, false).
+		AddItem(baseView, 0, 1, false).
+		AddItem(theirsView, 0, 1, false)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(sides, 0, 1, false).
+		AddItem(resultView, 0, 1, false).
+		AddItem(status, 1, 0, false)
+
+	render := func() {
+		oursView.SetText(mergePaneText(m, func(r diff.MergeRegion) []string { return r.Ours }))
+		baseView.SetText(mergePaneText(m, func(r diff.MergeRegion) []string { return r.Base }))
+		theirsView.SetText(mergePaneText(m, func(r diff.MergeRegion) []string { return r.Theirs }))
+		resultView.SetText(mergeResultText(m, style))
+
+		state := "No conflicts"
+		if len(conflicts) > 0 {
+			region := mergeRegionID(conflicts[current])
+			for _, tv := range []*tview.TextView{oursView, baseView, theirsView, resultView} {
+				tv.Highlight(region).ScrollToHighlight()
+			}
+			resolution := "[red]unresolved[white]"
+			if m.Regions[conflicts[current]].Resolved {
+				resolution = "[green]resolved[white]"
+			}
+			state = fmt.Sprintf("Conflict %d/%d %s, %d left", current+1, len(conflicts), resolution, m.Conflicts())
+		}
+		if message != "" {
+			state += "  [yellow]" + message + "[white]"
+			message = ""
+		}
+		status.SetText(state + "  |  " + mergeHelp)
+	}
+
+	// nextUnresolved moves to the next unresolved conflict after the
+	// current one, wrapping around, and stays put when there is none.
+	nextUnresolved := func() {
+		for step := 1; step <= len(conflicts); step++ {
+			i := (current + step) % len(conflicts)
+			if !m.Regions[conflicts[i]].Resolved {
+				current = i
+				return
+			}
+		}
+	}
+	resolve := func(lines []string) {
+		m.Resolve(conflicts[current], lines)
+		dirty = true
+		nextUnresolved()
+	}
+	save := func() {
+		var b bytes.Buffer
+		diff.WriteMerge(&b, m, style)
+		if err := os.WriteFile(outputPath, b.Bytes(), 0o644); err != nil {
+			message = "Error saving: " + err.Error()
+			return
+		}
+		saved, dirty = true, false
+		message = "Saved " + outputPath
+		if n := m.Conflicts(); n > 0 {
+			message += fmt.Sprintf(" with %d unresolved conflict(s)", n)
+		}
+	}
+
+	edit := func() {
+		r := m.Regions[conflicts[current]]
+		var initial string
+		if r.Resolved {
+			initial = joinLines(r.Resolution)
+		} else {
+			var b bytes.Buffer
+			diff.WriteMerge(&b, diff.Merge{Regions: []diff.MergeRegion{r}}, style)
+			initial = b.String()
+		}
+		area := tview.NewTextArea().SetText(initial, false)
+		area.SetBorder(true).SetTitle(" Edit conflict: Ctrl-S to accept, Esc to cancel ")
+		area.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			switch event.Key() {
+			case tcell.KeyCtrlS:
+				resolve(splitLines(area.GetText()))
+			case tcell.KeyEscape:
+			default:
+				return event
+			}
+			render()
+			app.SetRoot(layout, true)
+			return nil
+		})
+		app.SetRoot(area, true).SetFocus(area)
+	}
+
+	layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyCtrlC {
+			app.Stop()
+			return nil
+		}
+		if event.Key() != tcell.KeyRune && event.Key() != tcell.KeyEscape {
+			return event
+		}
+		r := event.Rune()
+		if event.Key() == tcell.KeyEscape {
+			r = 'q'
+		}
+		switch {
+		case r == 'q':
+			if !dirty {
+				app.Stop()
+				return nil
+			}
+			modal := tview.NewModal().
+				SetText("Quit without saving your resolutions?").
+				AddButtons([]string{"Quit", "Cancel"}).
+				SetDoneFunc(func(_ int, label string) {
+					if label == "Quit" {
+						app.Stop()
+						return
+					}
+					app.SetRoot(layout, true)
+				})
+			app.SetRoot(modal, false)
+			return nil
+		case r == 's':
+			save()
+		case len(conflicts) == 0:
+			return nil
+		case r == 'o':
+			resolve(m.Regions[conflicts[current]].Ours)
+		case r == 't':
+			resolve(m.Regions[conflicts[current]].Theirs)
+		case r == 'b':
+			r := m.Regions[conflicts[current]]
+			resolve(append(append([]string(nil), r.Ours...), r.Theirs...))
+		case r == 'u':
+			m.Unresolve(conflicts[current])
+			dirty = true
+		case r == 'e':
+			edit()
+			return nil
+		case r == 'n':
+			nextUnresolved()
+		case r == 'p':
+			current = (current + len(conflicts) - 1) % len(conflicts)
+		default:
+			return event
+		}
+		render()
+		return nil
+	})
+
+	render()
+	if err := app.SetRoot(layout, true).Run(); err != nil {
+		return false, err
+	}
+	return saved && !dirty && m.Conflicts() == 0, nil
+}
+
+// mergePaneText renders one input of a merge, region by region, coloring
+// the regions by type.
+func mergePaneText(m diff.Merge, side func(diff.MergeRegion) []string) string {
+	var b strings.Builder
+	for i, r := range m.Regions {
+		fmt.Fprintf(&b, `["%s"]`, mergeRegionID(i))
+		color := mergeColors[r.Type]
+		for _, line := range side(r) {
+			writeColored(&b, tview.Escape(line), color)
+		}
+		b.WriteString(`[""]`)
+	}
+	return b.String()
+}
+
+// mergeResultText renders the merge as WriteMerge would write it, with
+// unresolved conflicts in red and resolved ones in green.
+func mergeResultText(m diff.Merge, style diff.MergeStyle) string {
+	var b strings.Builder
+	for i, r := range m.Regions {
+		fmt.Fprintf(&b, `["%s"]`, mergeRegionID(i))
+		switch {
+		case r.Type == "conflict" && r.Resolved:
+			for _, line := range r.Resolution {
+				writeColored(&b, tview.Escape(line), "green")
+			}
+		case r.Type == "conflict":
+			var conflict bytes.Buffer
+			diff.WriteMerge(&conflict, diff.Merge{Regions: []diff.MergeRegion{r}}, style)
+			for _, line := range splitLines(conflict.String()) {
+				writeColored(&b, tview.Escape(line), "red")
+			}
+		case r.Type == "theirs":
+			for _, line := range r.Theirs {
+				b.WriteString(tview.Escape(line) + "\n")
+			}
+		default:
+			for _, line := range r.Ours {
+				b.WriteString(tview.Escape(line) + "\n")
+			}
+		}
+		b.WriteString(`[""]`)
+	}
+	return b.String()
+}
+
+func writeColored(b *strings.Builder, line, color string) {
+	if color == "" {
+		b.WriteString(line + "\n")
+		return
+	}
+	fmt.Fprintf(b, "[%s]%s[white]\n", color, line)
+}
+
+func mergeRegionID(i int) string {
+	return fmt.Sprintf("r%d", i)
+}
+
+// joinLines is the text of lines, each ending in a newline.
+func joinLines(lines []string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// splitLines undoes joinLines. A missing final newline is tolerated.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
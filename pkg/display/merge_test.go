@@ -0,0 +1,45 @@
+package display
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+func TestMergeResultText(t *testing.T) {
+	style := diff.MergeStyle{OursLabel: "ours", TheirsLabel: "theirs"}
+	tests := []struct {
+		name    string
+		resolve []string
+		want    string
+	}{
+		{
+			name: "unresolved",
+			want: `["r0"]a` + "\n" + `[""]["r1"][red]<<<<<<< ours[white]` + "\n" +
+				"[red]x[white]\n[red]=======[white]\n[red]y[white]\n[red]>>>>>>> theirs[white]\n" +
+				`[""]["r2"]c` + "\n" + `[""]`,
+		},
+		{
+			name:    "resolved",
+			resolve: []string{"x", "[y]"},
+			want: `["r0"]a` + "\n" + `[""]["r1"][green]x[white]` + "\n" +
+				`[green][y[][white]` + "\n" + `[""]["r2"]c` + "\n" + `[""]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := diff.Merge3(strings.NewReader("a\nb\nc\n"), strings.NewReader("a\nx\nc\n"), strings.NewReader("a\ny\nc\n"), diff.Options{})
+			if err != nil {
+				t.Fatalf("Merge3() error = %v", err)
+			}
+			if tt.resolve != nil {
+				m.Resolve(1, tt.resolve)
+			}
+			if got := mergeResultText(m, style); got != tt.want {
+				t.Errorf("mergeResultText() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
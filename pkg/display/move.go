@@ -0,0 +1,45 @@
+package display
+
+import (
+	"fmt"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+// moveLink is the first line of a moved block in the old and in the new
+// file.
+type moveLink struct {
+	oldLine int
+	newLine int
+}
+
+// moveLinks maps every moved block in diffs to where it starts on each side.
+func moveLinks(diffs []diff.Diff) map[int]moveLink {
+	links := make(map[int]moveLink)
+	for _, d := range diffs {
+		link := links[d.MoveID]
+		switch {
+		case d.Type == "move_from" && link.oldLine == 0:
+			link.oldLine = d.OldLine
+		case d.Type == "move_to" && link.newLine == 0:
+			link.newLine = d.NewLine
+		default:
+			continue
+		}
+		links[d.MoveID] = link
+	}
+	return links
+}
+
+// moveNote describes where the block starting at d went to or came from.
+// It is empty for every other line.
+func moveNote(d diff.Diff, links map[int]moveLink) string {
+	link := links[d.MoveID]
+	switch {
+	case d.Type == "move_from" && d.OldLine == link.oldLine:
+		return fmt.Sprintf("moved to line %d", link.newLine)
+	case d.Type == "move_to" && d.NewLine == link.newLine:
+		return fmt.Sprintf("moved from line %d", link.oldLine)
+	}
+	return ""
+}
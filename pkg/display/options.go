@@ -0,0 +1,69 @@
+package display
+
+import "github.com/san-kum/diff-dance/pkg/diff"
+
+// Options controls how diffs are rendered.
+type Options struct {
+	// Context is the number of unchanged lines shown around each change.
+	// A negative value shows every line.
+	Context int
+	// IntraLine is "word" or "char" to pair similar removed and added lines
+	// and emphasize what changed within them. Any other value shows whole
+	// lines only.
+	IntraLine string
+	// MoveLines is the number of non-blank lines a block needs to be shown
+	// as moved rather than removed and added. Zero turns move detection
+	// off.
+	MoveLines int
+
+	// SideBySide shows the old and new lines of the terminal rendering in
+	// two columns.
+	SideBySide bool
+	// Width is the total width of a side-by-side rendering. Zero means 80.
+	Width int
+	// TabSize is the distance between tab stops when tabs are expanded for
+	// side-by-side columns. Zero means 8.
+	TabSize int
+	// Wrap continues side-by-side lines that do not fit their column on
+	// the next line instead of cutting them off.
+	Wrap bool
+
+	// Template is a directory of *.html templates replacing the built-in
+	// ones of the HTML output, as whole files or as single {{define}}
+	// blocks of them.
+	Template string
+	// Theme is the color scheme of the HTML output: one of HTMLThemes or
+	// the path of a CSS file applied on top of the light theme. Empty
+	// means light.
+	Theme string
+
+	// MaxLines is the number of diff lines the Markdown output shows for
+	// each file before cutting the rest off. Zero shows every line.
+	MaxLines int
+}
+
+// columnWidth is the width of each side-by-side column, leaving room for
+// the gutter between them.
+func (o Options) columnWidth() int {
+	width := o.Width
+	if width <= 0 {
+		width = 80
+	}
+	return max((width-3)/2, 1)
+}
+
+func (o Options) tabSize() int {
+	if o.TabSize <= 0 {
+		return 8
+	}
+	return o.TabSize
+}
+
+// prepare detects moved blocks and pairs changed lines as configured.
+func (o Options) prepare(diffs []diff.Diff) []diff.Diff {
+	diffs = diff.DetectMoves(diffs, o.MoveLines)
+	if o.IntraLine == "word" || o.IntraLine == "char" {
+		diffs = diff.PairChanges(diffs)
+	}
+	return diffs
+}
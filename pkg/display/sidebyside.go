@@ -0,0 +1,210 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+// sideCell is one side of a side-by-side row.
+type sideCell struct {
+	text  string
+	spans []diff.Span
+	color func(string) string
+}
+
+// sideRow is a row of a side-by-side view, the old line next to the new
+// one. Either may be missing; unchanged lines and paired changes have the
+// same entry on both sides.
+type sideRow struct {
+	old, new *diff.Diff
+}
+
+// gutter is the marker sdiff draws between the sides of r: "|" for changed
+// lines, "<" for lines only on the left and ">" for lines only on the right.
+func (r sideRow) gutter() string {
+	switch {
+	case r.old == nil:
+		return ">"
+	case r.new == nil:
+		return "<"
+	case r.old.Type == "same":
+		return " "
+	}
+	return "|"
+}
+
+// sideRows lays out lines in rows. Removed and added lines are queued until
+// the run of changes ends and then paired up row by row.
+func sideRows(lines []diff.Diff) []sideRow {
+	var rows []sideRow
+	var lefts, rights []*diff.Diff
+	flush := func() {
+		for i := 0; i < max(len(lefts), len(rights)); i++ {
+			var row sideRow
+			if i < len(lefts) {
+				row.old = lefts[i]
+			}
+			if i < len(rights) {
+				row.new = rights[i]
+			}
+			rows = append(rows, row)
+		}
+		lefts, rights = nil, nil
+	}
+
+	for i := range lines {
+		d := &lines[i]
+		switch d.Type {
+		case "remove", "move_from":
+			lefts = append(lefts, d)
+		case "add", "move_to":
+			rights = append(rights, d)
+		default:
+			flush()
+			rows = append(rows, sideRow{old: d, new: d})
+		}
+	}
+	flush()
+	return rows
+}
+
+var (
+	oldSideColors = map[string]func(string) string{"remove": red, "change": red, "move_from": magenta}
+	newSideColors = map[string]func(string) string{"add": green, "change": green, "move_to": cyan}
+)
+
+// sideBySideHunk renders a hunk as two columns, old on the left and new on
+// the right.
+func sideBySideHunk(h diff.Hunk, w io.Writer, opts Options) {
+	fmt.Fprintln(w, cyan(h.Header()))
+	width := opts.columnWidth()
+	cell := func(text string, spans []diff.Span, color func(string) string) *sideCell {
+		if color == nil {
+			color = identity
+		}
+		return &sideCell{text: text, spans: spans, color: color}
+	}
+	for _, row := range sideRows(h.Lines) {
+		var left, right *sideCell
+		switch {
+		case row.old != nil && row.old.Type == "change":
+			oldSpans, newSpans := diff.IntraLine(row.old.OldText, row.old.Line, opts.IntraLine)
+			left, right = cell(row.old.OldText, oldSpans, red), cell(row.new.Line, newSpans, green)
+		default:
+			if row.old != nil {
+				left = cell(row.old.Line, nil, oldSideColors[row.old.Type])
+			}
+			if row.new != nil {
+				right = cell(row.new.Line, nil, newSideColors[row.new.Type])
+			}
+		}
+		writeSideRow(w, left, right, row.gutter(), width, opts)
+	}
+}
+
+// writeSideRow writes a row of two cells, either of which may be missing.
+// A wrapped cell takes several lines; the gutter is drawn on the first.
+func writeSideRow(w io.Writer, left, right *sideCell, gutter string, width int, opts Options) {
+	var leftLines, rightLines []string
+	if left != nil {
+		leftLines = layoutCell(left.text, left.spans, width, opts.tabSize(), opts.Wrap)
+	}
+	if right != nil {
+		rightLines = layoutCell(right.text, right.spans, width, opts.tabSize(), opts.Wrap)
+	}
+	for i := 0; i < max(len(leftLines), len(rightLines), 1); i++ {
+		var b strings.Builder
+		if i < len(leftLines) {
+			b.WriteString(left.color(leftLines[i]))
+			b.WriteString(strings.Repeat(" ", max(width-cellWidth(leftLines[i]), 0)))
+		} else {
+			b.WriteString(strings.Repeat(" ", width))
+		}
+		if i == 0 {
+			b.WriteString(" " + gutter + " ")
+		} else {
+			b.WriteString("   ")
+		}
+		if i < len(rightLines) {
+			b.WriteString(right.color(rightLines[i]))
+		}
+		fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+	}
+}
+
+// layoutCell fits s into lines at most width columns wide, measuring runes
+// by their display width so that wide CJK characters and emoji line up.
+// Tabs are expanded to the next multiple of tabSize. With wrap set, text
+// that does not fit continues on the next line; otherwise it is cut off.
+// The changed spans are shown in reverse video.
+func layoutCell(s string, spans []diff.Span, width, tabSize int, wrap bool) []string {
+	var lines []string
+	var b strings.Builder
+	col, span, marked := 0, 0, false
+	setMarked := func(on bool) {
+		if on != marked {
+			if on {
+				b.WriteString("\033[7m")
+			} else {
+				b.WriteString("\033[27m")
+			}
+			marked = on
+		}
+	}
+	// newLine ends the current line and reports whether there is room for
+	// another.
+	newLine := func() bool {
+		inSpan := marked
+		setMarked(false)
+		lines = append(lines, b.String())
+		b.Reset()
+		col = 0
+		if !wrap {
+			return false
+		}
+		setMarked(inSpan)
+		return true
+	}
+
+	for i, r := range s {
+		for span < len(spans) && i >= spans[span].End {
+			span++
+		}
+		inSpan := span < len(spans) && i >= spans[span].Start
+
+		text, w := string(r), runewidth.RuneWidth(r)
+		if r == '\t' {
+			w = tabSize - col%tabSize
+		}
+		if col > 0 && col+w > width {
+			switch {
+			case r == '\t' && !wrap && col < width:
+				// Pad to the edge; whatever follows is cut off.
+				w = width - col
+			case !newLine():
+				return lines
+			case r == '\t':
+				w = min(tabSize, width)
+			}
+		}
+		if r == '\t' {
+			text = strings.Repeat(" ", w)
+		}
+		setMarked(inSpan)
+		b.WriteString(text)
+		col += w
+	}
+	setMarked(false)
+	return append(lines, b.String())
+}
+
+// cellWidth is the display width of a laid out line, ignoring the escape
+// sequences layoutCell adds.
+func cellWidth(s string) int {
+	s = strings.NewReplacer("\033[7m", "", "\033[27m", "").Replace(s)
+	return runewidth.StringWidth(s)
+}
@@ -0,0 +1,69 @@
+package display
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+func TestLayoutCell(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		spans []diff.Span
+		width int
+		wrap  bool
+		want  []string
+	}{
+		{name: "fits", s: "abc", width: 5, want: []string{"abc"}},
+		{name: "cut off", s: "abcdefg", width: 5, want: []string{"abcde"}},
+		{name: "wrapped", s: "abcdefg", width: 3, wrap: true, want: []string{"abc", "def", "g"}},
+		{name: "tabs expanded", s: "a\tb\tc", width: 20, want: []string{"a   b   c"}},
+		{name: "tab at the edge", s: "abcd\te", width: 6, want: []string{"abcd  "}},
+		{name: "wide runes cut off whole", s: "a你好", width: 4, want: []string{"a你"}},
+		{name: "wide runes wrapped", s: "你好世界", width: 5, wrap: true, want: []string{"你好", "世界"}},
+		{name: "emoji", s: "😀😀x", width: 4, want: []string{"😀😀"}},
+		{
+			name:  "span across a wrap",
+			s:     "abcdef",
+			spans: []diff.Span{{Start: 2, End: 5}},
+			width: 4,
+			wrap:  true,
+			want:  []string{"ab\033[7mcd\033[27m", "\033[7me\033[27mf"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := layoutCell(tt.s, tt.spans, tt.width, 4, tt.wrap)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("layoutCell() = %q, want %q", got, tt.want)
+			}
+			for _, line := range got {
+				if w := cellWidth(line); w > tt.width {
+					t.Errorf("line %q is %d columns wide, more than %d", line, w, tt.width)
+				}
+			}
+		})
+	}
+}
+
+func TestTerminalSideBySide(t *testing.T) {
+	diffs, err := diff.Files(strings.NewReader("a\nb\nc\nd\n"), strings.NewReader("a\nB\nd\ne\n"), diff.Options{})
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	var b strings.Builder
+	Terminal(diffs, &b, Options{Context: 3, SideBySide: true, Width: 13})
+	want := cyan("@@ -1,4 +1,4 @@") + "\n" +
+		"a       a\n" +
+		red("b") + "     | " + green("B") + "\n" +
+		red("c") + "     <\n" +
+		"d       d\n" +
+		"      > " + green("e") + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("Terminal() =\n%q\nwant\n%q", got, want)
+	}
+}
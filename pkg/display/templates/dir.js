@@ -0,0 +1,34 @@
+(function () {
+  var status = document.getElementById("filter-status");
+  var path = document.getElementById("filter-path");
+
+  function filter() {
+    var text = path.value.toLowerCase();
+    document.querySelectorAll("[data-path]").forEach(function (e) {
+      var match = (status.value === "" || e.getAttribute("data-status") === status.value) &&
+        e.getAttribute("data-path").toLowerCase().indexOf(text) >= 0;
+      e.classList.toggle("hidden", !match);
+    });
+    // A directory stays visible while anything below it is, so the
+    // directories are visited deepest first.
+    var dirs = document.querySelectorAll("nav.tree li.dir");
+    for (var i = dirs.length - 1; i >= 0; i--) {
+      var matched = dirs[i].hasAttribute("data-path") && !dirs[i].classList.contains("hidden");
+      var shown = dirs[i].querySelector("li:not(.hidden)") !== null;
+      dirs[i].classList.toggle("hidden", !matched && !shown);
+    }
+  }
+  status.addEventListener("change", filter);
+  path.addEventListener("input", filter);
+
+  // Opening a link to a collapsed file expands it.
+  function expand() {
+    var target = document.getElementById(decodeURIComponent(location.hash.slice(1)));
+    if (target && target.tagName === "DETAILS") {
+      target.open = true;
+      target.scrollIntoView();
+    }
+  }
+  window.addEventListener("hashchange", expand);
+  expand();
+})();
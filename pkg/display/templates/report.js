@@ -0,0 +1,66 @@
+(function () {
+  var body = document.body;
+  var button = document.getElementById("toggle-view");
+  var current = -1;
+
+  function hunks() {
+    var view = body.classList.contains("unified") ? "unified" : "split";
+    // Hunks in collapsed or filtered out files have no layout.
+    return Array.prototype.filter.call(document.querySelectorAll("table." + view + " tr.hunk-header"), function (h) {
+      return h.offsetParent !== null;
+    });
+  }
+  function show(i) {
+    var list = hunks();
+    if (list.length === 0) {
+      return;
+    }
+    current = Math.max(0, Math.min(list.length - 1, i));
+    list.forEach(function (h, j) {
+      h.classList.toggle("current", j === current);
+    });
+    list[current].scrollIntoView({block: "start"});
+  }
+  function toggle() {
+    var unified = body.classList.toggle("unified");
+    if (button) {
+      button.textContent = unified ? "Split view" : "Unified view";
+    }
+    if (current >= 0) {
+      show(current);
+    }
+  }
+
+  // A custom template may leave the button out.
+  if (button) {
+    button.addEventListener("click", toggle);
+  }
+  document.addEventListener("click", function (e) {
+    var gap = e.target.getAttribute && e.target.getAttribute("data-gap");
+    if (gap) {
+      document.getElementById(gap).hidden = false;
+      e.target.closest("tbody").remove();
+    }
+  });
+  document.addEventListener("keydown", function (e) {
+    if (e.ctrlKey || e.metaKey || e.altKey || e.target.matches("input, select, textarea")) {
+      return;
+    }
+    switch (e.key) {
+    case "n":
+    case "j":
+      show(current + 1);
+      break;
+    case "p":
+    case "k":
+      show(current - 1);
+      break;
+    case "v":
+      toggle();
+      break;
+    default:
+      return;
+    }
+    e.preventDefault();
+  });
+})();
@@ -1,29 +1,59 @@
 package display
 
 import (
+	"context"
 	"fmt"
 	"io"
 
 	"github.com/san-kum/diff-dance/pkg/diff"
 )
 
-func Terminal(diffs []diff.Diff, w io.Writer) {
-	for _, d := range diffs {
+func Terminal(diffs []diff.Diff, w io.Writer, opts Options) {
+	diffs = opts.prepare(diffs)
+	links := moveLinks(diffs)
+	for _, h := range diff.Hunks(diffs, opts.Context) {
+		terminalHunk(h, links, w, opts)
+	}
+}
+
+// TerminalStream renders the diff of file1 and file2 like Terminal while it
+// is being computed, see diff.StreamHunks. Moves are only detected within a
+// hunk. It gives up with ctx.Err() once ctx is done.
+func TerminalStream(ctx context.Context, file1, file2 io.Reader, w io.Writer, diffOpts diff.Options, opts Options) error {
+	return diff.StreamHunksContext(ctx, file1, file2, diffOpts, opts.Context, func(h diff.Hunk) error {
+		h.Lines = opts.prepare(h.Lines)
+		terminalHunk(h, moveLinks(h.Lines), w, opts)
+		return nil
+	})
+}
+
+func terminalHunk(h diff.Hunk, links map[int]moveLink, w io.Writer, opts Options) {
+	if opts.SideBySide {
+		sideBySideHunk(h, w, opts)
+		return
+	}
+	fmt.Fprintln(w, cyan(h.Header()))
+	for _, d := range h.Lines {
 		switch d.Type {
 		case "add":
 			fmt.Fprintln(w, green("+ "+d.Line))
 		case "remove":
 			fmt.Fprintln(w, red("- "+d.Line))
 		case "change":
-			fmt.Fprintln(w, yellow("~ "+d.Line))
-			fmt.Fprintln(w, " "+d.Line)
+			oldSpans, newSpans := diff.IntraLine(d.OldText, d.Line, opts.IntraLine)
+			fmt.Fprintln(w, red("- "+markSpans(d.OldText, oldSpans, reverse, identity)))
+			fmt.Fprintln(w, green("+ "+markSpans(d.Line, newSpans, reverse, identity)))
+		case "move_from":
+			fmt.Fprintln(w, magenta("- "+d.Line)+terminalMoveNote(d, links))
+		case "move_to":
+			fmt.Fprintln(w, cyan("+ "+d.Line)+terminalMoveNote(d, links))
 		default:
 			fmt.Fprintln(w, d.Line)
 		}
 	}
 }
 
-func TerminalDir(diffs []diff.DirectoryDiff, w io.Writer) {
+func TerminalDir(diffs []diff.DirectoryDiff, w io.Writer, opts Options) {
 	for _, d := range diffs {
 		switch d.Type {
 		case "add":
@@ -35,7 +65,7 @@ func TerminalDir(diffs []diff.DirectoryDiff, w io.Writer) {
 				fmt.Fprintf(w, "%s %s\n", yellow("~"), fmt.Sprintf("Binary files differ: %s <-> %s", d.File1, d.File2))
 			} else {
 				fmt.Fprintf(w, "%s %s\n", yellow("~"), fmt.Sprintf("File: %s", d.File1))
-				Terminal(d.Diffs, w) //Recursive call to show changes
+				Terminal(d.Diffs, w, opts) //Recursive call to show changes
 			}
 		case "same":
 			fmt.Fprintf(w, "  %s\n", d.File1)
@@ -49,6 +79,14 @@ func TerminalDir(diffs []diff.DirectoryDiff, w io.Writer) {
 	}
 }
 
+// terminalMoveNote points the first line of a moved block at its other end.
+func terminalMoveNote(d diff.Diff, links map[int]moveLink) string {
+	if note := moveNote(d, links); note != "" {
+		return "  " + yellow("("+note+")")
+	}
+	return ""
+}
+
 func red(s string) string {
 	return "\033[31m" + s + "\033[0m"
 }
@@ -59,3 +97,11 @@ func green(s string) string {
 func yellow(s string) string {
 	return "\033[33m" + s + "\033[0m"
 }
+
+func cyan(s string) string {
+	return "\033[36m" + s + "\033[0m"
+}
+
+func magenta(s string) string {
+	return "\033[35m" + s + "\033[0m"
+}
@@ -0,0 +1,121 @@
+package display
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+// UnifiedFile names one side of a unified diff.
+type UnifiedFile struct {
+	Name    string
+	ModTime time.Time
+}
+
+func (f UnifiedFile) header() string {
+	if f.ModTime.IsZero() {
+		return f.Name
+	}
+	return f.Name + "\t" + f.ModTime.Format("2006-01-02 15:04:05.000000000 -0700")
+}
+
+// Unified writes diffs as a unified diff that patch and git apply accept.
+// Nothing is written when the inputs are identical.
+func Unified(diffs []diff.Diff, old, new UnifiedFile, w io.Writer, opts Options) error {
+	var b strings.Builder
+	writeUnified(&b, diffs, old, new, opts)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// UnifiedStream writes the unified diff of file1 and file2 while it is
+// being computed, see diff.StreamHunks. It gives up with ctx.Err() once ctx
+// is done.
+func UnifiedStream(ctx context.Context, file1, file2 io.Reader, old, new UnifiedFile, w io.Writer, diffOpts diff.Options, opts Options) error {
+	headers := false
+	return diff.StreamHunksContext(ctx, file1, file2, diffOpts, opts.Context, func(h diff.Hunk) error {
+		if !headers {
+			if _, err := fmt.Fprintf(w, "--- %s\n+++ %s\n", old.header(), new.header()); err != nil {
+				return err
+			}
+			headers = true
+		}
+		return diff.WriteHunk(w, h)
+	})
+}
+
+// UnifiedDir writes a unified diff for every changed file of a directory
+// diff. Files present on one side only are reported the way GNU diff -r
+// does, as are binary files.
+func UnifiedDir(diffs []diff.DirectoryDiff, dir1, dir2 string, w io.Writer, opts Options) error {
+	return writeDir(diffs, dir1, dir2, w, func(b *strings.Builder, d diff.DirectoryDiff, path1, path2 string) {
+		if d.BinaryDiff {
+			fmt.Fprintf(b, "Binary files %s and %s differ\n", path1, path2)
+		} else {
+			writeUnified(b, d.Diffs, UnifiedFile{Name: path1, ModTime: d.ModTime1}, UnifiedFile{Name: path2, ModTime: d.ModTime2}, opts)
+		}
+	})
+}
+
+// BriefDir reports which files of a directory diff differ, as GNU diff -rq
+// does.
+func BriefDir(diffs []diff.DirectoryDiff, dir1, dir2 string, w io.Writer) error {
+	return writeDir(diffs, dir1, dir2, w, func(b *strings.Builder, d diff.DirectoryDiff, path1, path2 string) {
+		fmt.Fprintf(b, "Files %s and %s differ\n", path1, path2)
+	})
+}
+
+// writeDir writes the "Only in" lines of a directory diff and calls change
+// for every changed file.
+func writeDir(diffs []diff.DirectoryDiff, dir1, dir2 string, w io.Writer, change func(b *strings.Builder, d diff.DirectoryDiff, path1, path2 string)) error {
+	var b strings.Builder
+
+	// Entries below an added or removed directory are covered by the
+	// directory's own "Only in" line.
+	onlyDirs := make(map[string]bool)
+	coveredBy := func(path string) bool {
+		for dir := filepath.Dir(path); dir != "."; dir = filepath.Dir(dir) {
+			if onlyDirs[dir] {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, d := range diffs {
+		switch d.Type {
+		case "change":
+			change(&b, d, filepath.Join(dir1, d.File1), filepath.Join(dir2, d.File2))
+		case "remove", "remove_dir":
+			if !coveredBy(d.File1) {
+				fmt.Fprintf(&b, "Only in %s: %s\n", filepath.Join(dir1, filepath.Dir(d.File1)), filepath.Base(d.File1))
+			}
+			if d.Type == "remove_dir" {
+				onlyDirs[d.File1] = true
+			}
+		case "add", "add_dir":
+			if !coveredBy(d.File2) {
+				fmt.Fprintf(&b, "Only in %s: %s\n", filepath.Join(dir2, filepath.Dir(d.File2)), filepath.Base(d.File2))
+			}
+			if d.Type == "add_dir" {
+				onlyDirs[d.File2] = true
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeUnified(b *strings.Builder, diffs []diff.Diff, old, new UnifiedFile, opts Options) {
+	hunks := diff.Hunks(diffs, opts.Context)
+	if len(hunks) == 0 {
+		return
+	}
+	diff.WritePatch(b, diff.FilePatch{OldName: old.header(), NewName: new.header(), Hunks: hunks})
+}
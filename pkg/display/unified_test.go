@@ -0,0 +1,72 @@
+package display
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name   string
+		input1 string
+		input2 string
+		want   string
+	}{
+		{
+			name:   "identical",
+			input1: "a\nb\n",
+			input2: "a\nb\n",
+			want:   "",
+		},
+		{
+			name:   "changed line",
+			input1: "a\nb\nc\n",
+			input2: "a\nx\nc\n",
+			want: "--- old\n+++ new\n" +
+				"@@ -1,3 +1,3 @@\n" +
+				" a\n-b\n+x\n c\n",
+		},
+		{
+			name:   "newline added at end of file",
+			input1: "a\nb",
+			input2: "a\nb\n",
+			want: "--- old\n+++ new\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				" a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name:   "unchanged last line without newline",
+			input1: "a\nb",
+			input2: "x\nb",
+			want: "--- old\n+++ new\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				"-a\n+x\n b\n\\ No newline at end of file\n",
+		},
+		{
+			name:   "new file",
+			input1: "",
+			input2: "a\n",
+			want: "--- old\n+++ new\n" +
+				"@@ -0,0 +1 @@\n" +
+				"+a\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := diff.Files(strings.NewReader(tt.input1), strings.NewReader(tt.input2), diff.Options{})
+			if err != nil {
+				t.Fatalf("Files() error = %v", err)
+			}
+			var b strings.Builder
+			if err := Unified(diffs, UnifiedFile{Name: "old"}, UnifiedFile{Name: "new"}, &b, Options{Context: 3}); err != nil {
+				t.Fatalf("Unified() error = %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+// Package git compares revisions, the index and the working tree of a git
+// repository by running the git binary.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+// Source is one side of a comparison: a revision such as "HEAD~2", or
+// Index or WorkTree.
+type Source string
+
+const (
+	Index    Source = ":index"
+	WorkTree Source = ":worktree"
+)
+
+// Name is how s is shown in output: the revision as given, "staging area"
+// for Index or "working tree" for WorkTree. Those contain a space, which
+// ref names cannot, so they are never mistaken for revisions.
+func (s Source) Name() string {
+	switch s {
+	case Index:
+		return "staging area"
+	case WorkTree:
+		return "working tree"
+	}
+	return string(s)
+}
+
+// Repo is a git repository. Commands run in the directory it was opened
+// in, so revisions and pathspecs mean what they would there. Root is the
+// top level of the working tree.
+type Repo struct {
+	Root string
+	dir  string
+}
+
+// Open finds the repository containing dir.
+func Open(dir string) (*Repo, error) {
+	r := &Repo{dir: dir}
+	out, err := r.git(context.Background(), "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	r.Root = strings.TrimSuffix(string(out), "\n")
+	return r, nil
+}
+
+// MergeBase returns the best common ancestor of two revisions, as used by
+// "git diff A...B".
+func (r *Repo) MergeBase(ctx context.Context, a, b Source) (Source, error) {
+	out, err := r.git(ctx, "merge-base", "--end-of-options", string(a), string(b))
+	if err != nil {
+		return "", err
+	}
+	return Source(strings.TrimSuffix(string(out), "\n")), nil
+}
+
+// Diff compares the files of old and new in the shape DirectoryDiffs
+// returns, so the directory renderers can show it. File1 and File2 are
+// slash-separated paths relative to Root. Only the files git reports as
+// changed are included, limited to those matching paths when any are given.
+// Once ctx is done it gives up with ctx.Err().
+func (r *Repo) Diff(ctx context.Context, old, new Source, paths []string, opts diff.Options) ([]diff.DirectoryDiff, error) {
+	changes, err := r.changes(ctx, old, new, paths)
+	if err != nil {
+		return nil, err
+	}
+	oldTime, newTime := r.revisionTime(ctx, old), r.revisionTime(ctx, new)
+
+	var diffs []diff.DirectoryDiff
+	for _, c := range changes {
+		switch c.status {
+		case "A":
+			diffs = append(diffs, diff.DirectoryDiff{File2: c.path, Type: "add"})
+			continue
+		case "D":
+			diffs = append(diffs, diff.DirectoryDiff{File1: c.path, Type: "remove"})
+			continue
+		}
+
+		content1, err := r.ReadFile(ctx, old, c.path)
+		if err != nil {
+			return nil, err
+		}
+		content2, err := r.ReadFile(ctx, new, c.path)
+		if err != nil {
+			return nil, err
+		}
+		fileDiffs, binDiff, err := diff.FilesDiffContext(ctx, bytes.NewReader(content1), bytes.NewReader(content2), opts)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, fmt.Errorf("diffing %s: %w", c.path, err)
+		}
+
+		d := diff.DirectoryDiff{File1: c.path, File2: c.path, Type: "change", ModTime1: oldTime, ModTime2: newTime}
+		if old == WorkTree {
+			d.ModTime1 = r.fileTime(c.path)
+		}
+		if new == WorkTree {
+			d.ModTime2 = r.fileTime(c.path)
+		}
+		switch {
+		case binDiff:
+			d.BinaryDiff = true
+		case diff.HasChanges(fileDiffs):
+			d.Diffs = fileDiffs
+		default:
+			// A change git sees but the options hide, such as one of
+			// white space or of the file mode.
+			d.Type = "same"
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// ReadFile returns the contents of path, relative to Root, in src. An
+// unmerged path reads from the index as our side of the conflict. A
+// symbolic link in the working tree reads as its target, as git stores it.
+func (r *Repo) ReadFile(ctx context.Context, src Source, path string) ([]byte, error) {
+	switch src {
+	case WorkTree:
+		name := filepath.Join(r.Root, filepath.FromSlash(path))
+		info, err := os.Lstat(name)
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(name)
+			return []byte(target), err
+		}
+		return os.ReadFile(name)
+	case Index:
+		content, err := r.git(ctx, "cat-file", "blob", ":0:"+path)
+		if err == nil {
+			return content, nil
+		}
+		if ours, oursErr := r.git(ctx, "cat-file", "blob", ":2:"+path); oursErr == nil {
+			return ours, nil
+		}
+		return nil, err
+	default:
+		return r.git(ctx, "cat-file", "blob", string(src)+":"+path)
+	}
+}
+
+// change is a path git diff --name-status reports, with its status letter.
+type change struct {
+	status string
+	path   string
+}
+
+// changes lists the paths that differ between old and new. git diff only
+// compares in certain directions, so the others are asked for in reverse.
+func (r *Repo) changes(ctx context.Context, old, new Source, paths []string) ([]change, error) {
+	if old == new {
+		return nil, nil
+	}
+	args := []string{"diff", "--name-status", "-z", "--no-renames", "--ignore-submodules"}
+	var revs []string
+	switch {
+	case old == Index && new == WorkTree:
+	case old == WorkTree && new == Index:
+		args = append(args, "-R")
+	case new == WorkTree:
+		revs = []string{string(old)}
+	case old == WorkTree:
+		args = append(args, "-R")
+		revs = []string{string(new)}
+	case new == Index:
+		args = append(args, "--cached")
+		revs = []string{string(old)}
+	case old == Index:
+		args = append(args, "--cached", "-R")
+		revs = []string{string(new)}
+	default:
+		revs = []string{string(old), string(new)}
+	}
+	args = append(args, "--end-of-options")
+	args = append(args, revs...)
+	args = append(args, "--")
+	args = append(args, paths...)
+
+	out, err := r.git(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("git diff: unexpected output %q", out)
+	}
+	var changes []change
+	for i := 0; i+1 < len(fields); i += 2 {
+		changes = append(changes, change{status: fields[i], path: fields[i+1]})
+	}
+	return changes, nil
+}
+
+// revisionTime is the committer date of a revision. It is zero for the
+// index, the working tree and revisions that do not name a commit.
+func (r *Repo) revisionTime(ctx context.Context, src Source) time.Time {
+	if src == Index || src == WorkTree {
+		return time.Time{}
+	}
+	out, err := r.git(ctx, "show", "-s", "--format=%ct", "--end-of-options", string(src)+"^{commit}")
+	if err != nil {
+		return time.Time{}
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// fileTime is the modification time of path in the working tree, or zero
+// when it cannot be read.
+func (r *Repo) fileTime(path string) time.Time {
+	info, err := os.Lstat(filepath.Join(r.Root, filepath.FromSlash(path)))
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// git runs a git command and returns its output. Failures carry git's own
+// error message.
+func (r *Repo) git(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.dir
+	out, err := cmd.Output()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if msg := strings.TrimSpace(string(exitErr.Stderr)); msg != "" {
+				return nil, fmt.Errorf("git %s: %s", args[0], msg)
+			}
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
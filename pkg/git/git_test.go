@@ -0,0 +1,241 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/san-kum/diff-dance/pkg/diff"
+)
+
+// testRepo creates a repository with two commits and uncommitted changes:
+//
+//	HEAD~1  a.txt "a\n", gone.txt, bin.dat
+//	HEAD    a.txt "a\nb\n", new.txt, bin.dat changed
+//	index   a.txt "a\nb\nc\n"
+//	files   a.txt "a\nb\nc\nd\n"
+func testRepo(t *testing.T) *Repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q")
+	write("a.txt", "a\n")
+	write("gone.txt", "gone\n")
+	write("bin.dat", "\x00\x01")
+	run("add", "-A")
+	run("commit", "-q", "-m", "first")
+	write("a.txt", "a\nb\n")
+	write("new.txt", "new\n")
+	write("bin.dat", "\x00\x02")
+	if err := os.Remove(filepath.Join(dir, "gone.txt")); err != nil {
+		t.Fatal(err)
+	}
+	run("add", "-A")
+	run("commit", "-q", "-m", "second")
+	write("a.txt", "a\nb\nc\n")
+	run("add", "a.txt")
+	write("a.txt", "a\nb\nc\nd\n")
+
+	repo, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return repo
+}
+
+// summary reduces directory diffs to their types and the changed lines of
+// their files.
+func summary(diffs []diff.DirectoryDiff) []string {
+	var got []string
+	for _, d := range diffs {
+		s := d.Type + " " + d.File1 + " " + d.File2
+		if d.BinaryDiff {
+			s += " binary"
+		}
+		for _, fd := range d.Diffs {
+			switch fd.Type {
+			case "add":
+				s += " +" + fd.Line
+			case "remove":
+				s += " -" + fd.Line
+			}
+		}
+		got = append(got, s)
+	}
+	return got
+}
+
+func TestDiff(t *testing.T) {
+	repo := testRepo(t)
+	tests := []struct {
+		name  string
+		old   Source
+		new   Source
+		paths []string
+		want  []string
+	}{
+		{
+			name: "index against working tree",
+			old:  Index,
+			new:  WorkTree,
+			want: []string{"change a.txt a.txt +d"},
+		},
+		{
+			name: "working tree against index",
+			old:  WorkTree,
+			new:  Index,
+			want: []string{"change a.txt a.txt -d"},
+		},
+		{
+			name: "HEAD against index",
+			old:  "HEAD",
+			new:  Index,
+			want: []string{"change a.txt a.txt +c"},
+		},
+		{
+			name: "index against HEAD",
+			old:  Index,
+			new:  "HEAD",
+			want: []string{"change a.txt a.txt -c"},
+		},
+		{
+			name: "revision against working tree",
+			old:  "HEAD~1",
+			new:  WorkTree,
+			want: []string{"change a.txt a.txt +b +c +d", "change bin.dat bin.dat binary", "remove gone.txt ", "add  new.txt"},
+		},
+		{
+			name: "working tree against revision",
+			old:  WorkTree,
+			new:  "HEAD~1",
+			want: []string{"change a.txt a.txt -b -c -d", "change bin.dat bin.dat binary", "add  gone.txt", "remove new.txt "},
+		},
+		{
+			name: "two revisions",
+			old:  "HEAD~1",
+			new:  "HEAD",
+			want: []string{"change a.txt a.txt +b", "change bin.dat bin.dat binary", "remove gone.txt ", "add  new.txt"},
+		},
+		{
+			name:  "limited to paths",
+			old:   "HEAD~1",
+			new:   "HEAD",
+			paths: []string{"*.txt"},
+			want:  []string{"change a.txt a.txt +b", "remove gone.txt ", "add  new.txt"},
+		},
+		{
+			name: "same source",
+			old:  "HEAD",
+			new:  "HEAD",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := repo.Diff(context.Background(), tt.old, tt.new, tt.paths, diff.Options{})
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+			if got := summary(diffs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffErrors(t *testing.T) {
+	repo := testRepo(t)
+	if _, err := repo.Diff(context.Background(), "no-such-revision", WorkTree, nil, diff.Options{}); err == nil {
+		t.Error("Diff() with an unknown revision succeeded")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := repo.Diff(ctx, "HEAD~1", "HEAD", nil, diff.Options{}); err != context.Canceled {
+		t.Errorf("Diff() with a cancelled context error = %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := Open(t.TempDir()); err == nil {
+		t.Error("Open() outside a repository succeeded")
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	repo := testRepo(t)
+	tests := []struct {
+		src  Source
+		want string
+	}{
+		{src: "HEAD~1", want: "a\n"},
+		{src: "HEAD", want: "a\nb\n"},
+		{src: Index, want: "a\nb\nc\n"},
+		{src: WorkTree, want: "a\nb\nc\nd\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.src), func(t *testing.T) {
+			got, err := repo.ReadFile(context.Background(), tt.src, "a.txt")
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ReadFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeBase(t *testing.T) {
+	repo := testRepo(t)
+	base, err := repo.MergeBase(context.Background(), "HEAD~1", "HEAD")
+	if err != nil {
+		t.Fatalf("MergeBase() error = %v", err)
+	}
+	diffs, err := repo.Diff(context.Background(), base, "HEAD~1", nil, diff.Options{})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("merge base of HEAD~1 and HEAD differs from HEAD~1: %q", summary(diffs))
+	}
+}
+
+func TestSourceName(t *testing.T) {
+	tests := []struct {
+		source Source
+		want   string
+	}{
+		{Index, "staging area"},
+		{WorkTree, "working tree"},
+		{"HEAD~2", "HEAD~2"},
+	}
+	for _, tt := range tests {
+		if got := tt.source.Name(); got != tt.want {
+			t.Errorf("Source(%q).Name() = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
@@ -3,15 +3,27 @@ package utils
 import (
 	"bufio"
 	"io"
+	"strings"
 )
 
+// ReadLines splits r into lines, dropping "\n" and "\r\n" line endings.
+// Unlike bufio.Scanner it accepts lines of any length.
 func ReadLines(r io.Reader) ([]string, error) {
 	var lines []string
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			line = strings.TrimSuffix(line, "\n")
+			lines = append(lines, strings.TrimSuffix(line, "\r"))
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
 	}
-	return lines, scanner.Err()
 }
 
 func Max(x, y int) int {